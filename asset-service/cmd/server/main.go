@@ -47,8 +47,10 @@ func main() {
 	// Initialize handler
 	assetHandler := handler.NewAssetHandler(assetService)
 
-	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(userServiceClient)
+	// Initialize middleware, verifying tokens with user service's published keys
+	userServiceURL := getEnv("USER_SERVICE_URL", "http://localhost:8080")
+	jwks := middleware.NewJWKSCache(getEnv("JWKS_URL", userServiceURL+"/.well-known/jwks.json"))
+	authMiddleware := middleware.NewAuthMiddleware(jwks, redisClient)
 
 	// Initialize Gin router
 	r := gin.Default()
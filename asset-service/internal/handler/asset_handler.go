@@ -30,8 +30,9 @@ func (h *AssetHandler) CreateFolder(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 
-	folder, err := h.assetService.CreateFolder(&req, userID)
+	folder, err := h.assetService.CreateFolder(&req, userID, actedBy)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -95,9 +96,10 @@ func (h *AssetHandler) DeleteFolder(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 	userRole, _ := middleware.GetUserRole(c)
 
-	if err := h.assetService.DeleteFolder(folderID, userID, userRole); err != nil {
+	if err := h.assetService.DeleteFolder(folderID, userID, userRole, actedBy); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
@@ -167,8 +169,9 @@ func (h *AssetHandler) UpdateNote(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 	userRole, _ := middleware.GetUserRole(c)
-	note, err := h.assetService.UpdateNote(noteID, &req, userID, userRole)
+	note, err := h.assetService.UpdateNote(noteID, &req, userID, userRole, actedBy)
 	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
@@ -186,9 +189,10 @@ func (h *AssetHandler) DeleteNote(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 	userRole, _ := middleware.GetUserRole(c)
 
-	if err := h.assetService.DeleteNote(noteID, userID, userRole); err != nil {
+	if err := h.assetService.DeleteNote(noteID, userID, userRole, actedBy); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
@@ -212,9 +216,10 @@ func (h *AssetHandler) ShareFolder(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 	token, _ := middleware.GetToken(c)
 
-	if err := h.assetService.ShareFolder(folderID, &req, userID, token); err != nil {
+	if err := h.assetService.ShareFolder(folderID, &req, userID, token, actedBy); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
@@ -238,8 +243,9 @@ func (h *AssetHandler) RevokeFolderSharing(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 
-	if err := h.assetService.RevokeFolderSharing(folderID, targetUserID, userID); err != nil {
+	if err := h.assetService.RevokeFolderSharing(folderID, targetUserID, userID, actedBy); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
@@ -263,9 +269,10 @@ func (h *AssetHandler) ShareNote(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 	token, _ := middleware.GetToken(c)
 
-	if err := h.assetService.ShareNote(noteID, &req, userID, token); err != nil {
+	if err := h.assetService.ShareNote(noteID, &req, userID, token, actedBy); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
@@ -289,8 +296,9 @@ func (h *AssetHandler) RevokeNoteSharing(c *gin.Context) {
 	}
 
 	userID, _ := middleware.GetUserID(c)
+	actedBy, _ := middleware.GetActorID(c)
 
-	if err := h.assetService.RevokeNoteSharing(noteID, targetUserID, userID); err != nil {
+	if err := h.assetService.RevokeNoteSharing(noteID, targetUserID, userID, actedBy); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
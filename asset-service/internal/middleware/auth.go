@@ -2,23 +2,50 @@ package middleware
 
 import (
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 
 	"asset-service/internal/model"
-	"asset-service/internal/service"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
 )
 
+// Revocation keys must match the ones user-service writes
+const (
+	revokedTokenKeyPrefix   = "auth:revoked:"
+	revokedUserKeyPrefix    = "auth:revoked-user:"
+	revokedSessionKeyPrefix = "auth:revoked-session:"
+)
+
+// tokenClaims are the access token claims issued by user service
+type tokenClaims struct {
+	UserID string `json:"userId"`
+	Role   string `json:"role"`
+	// FamilyID identifies the login session the token belongs to
+	FamilyID string `json:"fid,omitempty"`
+	// Actor is set when an admin impersonates UserID
+	Actor *tokenActor `json:"act,omitempty"`
+	jwt.StandardClaims
+}
+
+// tokenActor is the act claim of impersonation tokens
+type tokenActor struct {
+	UserID string `json:"sub"`
+}
+
 type AuthMiddleware struct {
-	userServiceClient *service.UserServiceClient
+	jwks  *JWKSCache
+	redis *redis.Client
 }
 
-func NewAuthMiddleware(userServiceClient *service.UserServiceClient) *AuthMiddleware {
+func NewAuthMiddleware(jwks *JWKSCache, redisClient *redis.Client) *AuthMiddleware {
 	return &AuthMiddleware{
-		userServiceClient: userServiceClient,
+		jwks:  jwks,
+		redis: redisClient,
 	}
 }
 
@@ -41,16 +68,39 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 
 		token := tokenParts[1]
 
-		// Validate token with user service
-		userInfo, err := m.userServiceClient.ValidateToken(token)
-		fmt.Println("user", userInfo)
-		fmt.Println("err", err)
-		if err != nil {
+		// Verify the token locally with user service's published keys
+		claims := &tokenClaims{}
+		parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			kid, _ := t.Header["kid"].(string)
+			return m.jwks.Key(kid)
+		})
+		if err != nil || !parsed.Valid {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		// Reject tokens revoked in user service
+		revoked, err := m.isRevoked(c, claims)
+		if err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+			c.Abort()
+			return
+		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		userInfo := &model.UserInfo{
+			UserID: claims.UserID,
+			Role:   claims.Role,
+		}
+
 		// Parse user ID
 		userID, err := uuid.Parse(userInfo.UserID)
 		if err != nil {
@@ -65,10 +115,59 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		c.Set("user_info", userInfo)
 		c.Set("token", token)
 
+		// Support staff acting as the user, logged so their actions can be
+		// told apart from the user's
+		if claims.Actor != nil {
+			c.Set("actor_id", claims.Actor.UserID)
+			log.Printf("[INFO] Impersonated request: admin %s as user %s: %s %s",
+				claims.Actor.UserID, claims.UserID, c.Request.Method, c.Request.URL.Path)
+		}
+
 		c.Next()
 	}
 }
 
+// isRevoked checks the revocation lists for the token's jti, session, user
+// and impersonating admin
+func (m *AuthMiddleware) isRevoked(c *gin.Context, claims *tokenClaims) (bool, error) {
+	if claims.Id != "" {
+		n, err := m.redis.Exists(c.Request.Context(), revokedTokenKeyPrefix+claims.Id).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	if claims.FamilyID != "" {
+		n, err := m.redis.Exists(c.Request.Context(), revokedSessionKeyPrefix+claims.FamilyID).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	revoked, err := m.isUserRevoked(c, claims.UserID, claims.IssuedAt)
+	if err != nil || revoked || claims.Actor == nil {
+		return revoked, err
+	}
+	return m.isUserRevoked(c, claims.Actor.UserID, claims.IssuedAt)
+}
+
+func (m *AuthMiddleware) isUserRevoked(c *gin.Context, userID string, issuedAt int64) (bool, error) {
+	revokedAt, err := m.redis.Get(c.Request.Context(), revokedUserKeyPrefix+userID).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return issuedAt < revokedAt, nil
+}
+
 func (m *AuthMiddleware) RequireManager() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
@@ -78,7 +177,7 @@ func (m *AuthMiddleware) RequireManager() gin.HandlerFunc {
 			return
 		}
 
-		if userRole.(string) != "manager" {
+		if !model.HasRole(userRole.(string), model.RoleManager) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Manager access required"})
 			c.Abort()
 			return
@@ -113,6 +212,15 @@ func GetUserInfo(c *gin.Context) (*model.UserInfo, bool) {
 	return userInfo.(*model.UserInfo), true
 }
 
+// GetActorID returns the admin impersonating the user, if any
+func GetActorID(c *gin.Context) (string, bool) {
+	actorID, exists := c.Get("actor_id")
+	if !exists {
+		return "", false
+	}
+	return actorID.(string), true
+}
+
 func GetToken(c *gin.Context) (string, bool) {
 	token, exists := c.Get("token")
 	if !exists {
@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// jwksTTL is how long fetched keys are trusted before refetching
+	jwksTTL = 10 * time.Minute
+	// jwksMinRefresh limits refetches triggered by unknown kids
+	jwksMinRefresh = 30 * time.Second
+	// jwksFetchTimeout bounds how long a request waits for user-service
+	jwksFetchTimeout = 5 * time.Second
+)
+
+// JWKSCache verifies user-service tokens locally with the public keys
+// published at its /.well-known/jwks.json endpoint
+type JWKSCache struct {
+	url        string
+	httpClient *http.Client
+
+	// fetchMu serializes fetches and guards attemptedAt; mu only guards the
+	// keys, so requests with a cached kid never wait on user-service
+	fetchMu     sync.Mutex
+	attemptedAt time.Time
+
+	mu        sync.RWMutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+func NewJWKSCache(url string) *JWKSCache {
+	return &JWKSCache{
+		url:        url,
+		httpClient: &http.Client{Timeout: jwksFetchTimeout},
+		keys:       make(map[string]*rsa.PublicKey),
+	}
+}
+
+// Key returns the public key for kid. A stale cache is refreshed in the
+// background while known keys keep verifying; only an unknown kid (e.g. right
+// after a key rotation) waits for the JWKS to be refetched
+func (c *JWKSCache) Key(kid string) (*rsa.PublicKey, error) {
+	c.mu.RLock()
+	key, ok := c.keys[kid]
+	fresh := time.Since(c.fetchedAt) < jwksTTL
+	c.mu.RUnlock()
+
+	if ok {
+		if !fresh {
+			go c.refreshInBackground()
+		}
+		return key, nil
+	}
+
+	if err := c.refresh(); err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	key, ok = c.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return key, nil
+}
+
+// refreshInBackground refreshes unless a fetch is already running
+func (c *JWKSCache) refreshInBackground() {
+	if !c.fetchMu.TryLock() {
+		return
+	}
+	defer c.fetchMu.Unlock()
+	_ = c.fetchLocked()
+}
+
+func (c *JWKSCache) refresh() error {
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+	return c.fetchLocked()
+}
+
+// fetchLocked fetches the JWKS and swaps in its keys. The caller holds fetchMu
+func (c *JWKSCache) fetchLocked() error {
+	// Another request may have refreshed, or failed to, while we waited for
+	// the lock
+	if time.Since(c.attemptedAt) < jwksMinRefresh {
+		return nil
+	}
+	c.attemptedAt = time.Now()
+
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return fmt.Errorf("failed to decode JWKS: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	c.mu.Lock()
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	c.mu.Unlock()
+	return nil
+}
@@ -26,7 +26,7 @@ func NewAssetService(db *gorm.DB, userServiceClient *UserServiceClient,
 }
 
 // Folder CRUD Operations
-func (s *AssetService) CreateFolder(req *model.CreateFolderRequest, ownerID uuid.UUID) (*model.FolderResponse, error) {
+func (s *AssetService) CreateFolder(req *model.CreateFolderRequest, ownerID uuid.UUID, actedBy string) (*model.FolderResponse, error) {
 	folder := &model.Folder{
 		Name:        req.Name,
 		Description: req.Description,
@@ -45,7 +45,7 @@ func (s *AssetService) CreateFolder(req *model.CreateFolderRequest, ownerID uuid
 		"actionBy":  ownerID.String(),
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), folder.ID.String(), event)
+	s.publish(folder.ID.String(), event, actedBy)
 
 	key := fmt.Sprintf("folder:%s", folder.ID.String())
 	data, _ := json.Marshal(folder)
@@ -61,7 +61,7 @@ func (s *AssetService) GetFolder(folderID uuid.UUID, userID uuid.UUID, userRole
 	query := s.db.Where("id = ?", folderID)
 	
 	// If not owner and not manager, check if folder is shared with user
-	if userRole != "manager" {
+	if !model.HasRole(userRole, model.RoleManager) {
 		query = query.Where("owner_id = ? OR id IN (SELECT folder_id FROM folder_shares WHERE user_id = ?)", userID, userID)
 	}
 	
@@ -80,7 +80,7 @@ func (s *AssetService) UpdateFolder(folderID uuid.UUID, req *model.UpdateFolderR
 	
 	// Check permissions - only owner or users with write access can update
 	query := s.db.Where("id = ?", folderID)
-	if userRole != "manager" {
+	if !model.HasRole(userRole, model.RoleManager) {
 		query = query.Where("owner_id = ? OR id IN (SELECT folder_id FROM folder_shares WHERE user_id = ? AND permission = 'write')", userID, userID)
 	}
 	
@@ -106,7 +106,7 @@ func (s *AssetService) UpdateFolder(folderID uuid.UUID, req *model.UpdateFolderR
 	return s.folderToResponse(&folder), nil
 }
 
-func (s *AssetService) DeleteFolder(folderID uuid.UUID, userID uuid.UUID, userRole string) error {
+func (s *AssetService) DeleteFolder(folderID uuid.UUID, userID uuid.UUID, userRole string, actedBy string) error {
 	var folder model.Folder
 	
 	// Only owner can delete folder
@@ -132,7 +132,7 @@ func (s *AssetService) DeleteFolder(folderID uuid.UUID, userID uuid.UUID, userRo
 		"actionBy":  userID.String(),
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), folder.ID.String(), event)
+	s.publish(folder.ID.String(), event, actedBy)
 
 	key := fmt.Sprintf("folder:%s", folder.ID.String())
 	s.redis.Del(context.Background(), key)
@@ -146,7 +146,7 @@ func (s *AssetService) CreateNote(folderID uuid.UUID, req *model.CreateNoteReque
 	var folder model.Folder
 	query := s.db.Where("id = ?", folderID)
 	
-	if userRole != "manager" {
+	if !model.HasRole(userRole, model.RoleManager) {
 		query = query.Where("owner_id = ? OR id IN (SELECT folder_id FROM folder_shares WHERE user_id = ? AND permission = 'write')", userID, userID)
 	}
 	
@@ -177,7 +177,7 @@ func (s *AssetService) GetNote(noteID uuid.UUID, userID uuid.UUID, userRole stri
 	query := s.db.Where("id = ?", noteID)
 	
 	// If not manager, check permissions
-	if userRole != "manager" {
+	if !model.HasRole(userRole, model.RoleManager) {
 		query = query.Where(`owner_id = ? OR 
 			folder_id IN (SELECT folder_id FROM folder_shares WHERE user_id = ?) OR
 			id IN (SELECT note_id FROM note_shares WHERE user_id = ?)`, userID, userID, userID)
@@ -193,13 +193,13 @@ func (s *AssetService) GetNote(noteID uuid.UUID, userID uuid.UUID, userRole stri
 	return s.noteToResponse(&note), nil
 }
 
-func (s *AssetService) UpdateNote(noteID uuid.UUID, req *model.UpdateNoteRequest, userID uuid.UUID, userRole string) (*model.NoteResponse, error) {
+func (s *AssetService) UpdateNote(noteID uuid.UUID, req *model.UpdateNoteRequest, userID uuid.UUID, userRole string, actedBy string) (*model.NoteResponse, error) {
 	var note model.Note
 	
 	query := s.db.Where("id = ?", noteID)
 	
 	// Check write permissions
-	if userRole != "manager" {
+	if !model.HasRole(userRole, model.RoleManager) {
 		query = query.Where(`owner_id = ? OR 
 			folder_id IN (SELECT folder_id FROM folder_shares WHERE user_id = ? AND permission = 'write') OR
 			id IN (SELECT note_id FROM note_shares WHERE user_id = ? AND permission = 'write')`, userID, userID, userID)
@@ -232,7 +232,7 @@ func (s *AssetService) UpdateNote(noteID uuid.UUID, req *model.UpdateNoteRequest
         "actionBy":  userID.String(),
         "timestamp": time.Now().UTC().Format(time.RFC3339),
     }
-    _ = s.kafka.Publish(context.Background(), note.ID.String(), event)
+    s.publish(note.ID.String(), event, actedBy)
 
     // ✅ Update Redis metadata cache
     key := fmt.Sprintf("note:%s", note.ID.String())
@@ -242,7 +242,7 @@ func (s *AssetService) UpdateNote(noteID uuid.UUID, req *model.UpdateNoteRequest
     return s.noteToResponse(&note), nil
 }
 
-func (s *AssetService) DeleteNote(noteID uuid.UUID, userID uuid.UUID, userRole string) error {
+func (s *AssetService) DeleteNote(noteID uuid.UUID, userID uuid.UUID, userRole string, actedBy string) error {
 	var note model.Note
 	
 	// Only owner can delete note
@@ -267,7 +267,7 @@ func (s *AssetService) DeleteNote(noteID uuid.UUID, userID uuid.UUID, userRole s
 		"actionBy":  userID.String(),
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), note.ID.String(), event)
+	s.publish(note.ID.String(), event, actedBy)
 
 	key := fmt.Sprintf("note:%s", note.ID.String())
 	s.redis.Del(context.Background(), key)
@@ -276,7 +276,7 @@ func (s *AssetService) DeleteNote(noteID uuid.UUID, userID uuid.UUID, userRole s
 }
 
 // Sharing Operations
-func (s *AssetService) ShareFolder(folderID uuid.UUID, req *model.ShareRequest, sharedBy uuid.UUID, token string) error {
+func (s *AssetService) ShareFolder(folderID uuid.UUID, req *model.ShareRequest, sharedBy uuid.UUID, token string, actedBy string) error {
 	// Check if folder exists and user is owner
 	var folder model.Folder
 	if err := s.db.Where("id = ? AND owner_id = ?", folderID, sharedBy).First(&folder).Error; err != nil {
@@ -320,7 +320,7 @@ func (s *AssetService) ShareFolder(folderID uuid.UUID, req *model.ShareRequest,
 		"permission": req.Permission,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), folderID.String(), event)
+	s.publish(folderID.String(), event, actedBy)
 
 	aclKey := fmt.Sprintf("asset:%s:acl", folderID.String())
 	s.redis.HSet(context.Background(), aclKey, req.UserID.String(), req.Permission)
@@ -328,7 +328,7 @@ func (s *AssetService) ShareFolder(folderID uuid.UUID, req *model.ShareRequest,
 	return nil
 }
 
-func (s *AssetService) RevokeFolderSharing(folderID uuid.UUID, targetUserID uuid.UUID, ownerID uuid.UUID) error {
+func (s *AssetService) RevokeFolderSharing(folderID uuid.UUID, targetUserID uuid.UUID, ownerID uuid.UUID, actedBy string) error {
 	// Check if folder exists and user is owner
 	var folder model.Folder
 	if err := s.db.Where("id = ? AND owner_id = ?", folderID, ownerID).First(&folder).Error; err != nil {
@@ -350,7 +350,7 @@ func (s *AssetService) RevokeFolderSharing(folderID uuid.UUID, targetUserID uuid
 		"targetUserId": targetUserID.String(),
 		"timestamp":   time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), folderID.String(), event)
+	s.publish(folderID.String(), event, actedBy)
 
 	aclKey := fmt.Sprintf("asset:%s:acl", folderID.String())
 	s.redis.HDel(context.Background(), aclKey, targetUserID.String())
@@ -358,7 +358,7 @@ func (s *AssetService) RevokeFolderSharing(folderID uuid.UUID, targetUserID uuid
 	return nil
 }
 
-func (s *AssetService) ShareNote(noteID uuid.UUID, req *model.ShareRequest, sharedBy uuid.UUID, token string) error {
+func (s *AssetService) ShareNote(noteID uuid.UUID, req *model.ShareRequest, sharedBy uuid.UUID, token string, actedBy string) error {
 	// Check if note exists and user is owner
 	var note model.Note
 	if err := s.db.Where("id = ? AND owner_id = ?", noteID, sharedBy).First(&note).Error; err != nil {
@@ -403,7 +403,7 @@ func (s *AssetService) ShareNote(noteID uuid.UUID, req *model.ShareRequest, shar
 		"permission": req.Permission,
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), noteID.String(), event)
+	s.publish(noteID.String(), event, actedBy)
 
 	aclKey := fmt.Sprintf("asset:%s:acl", noteID.String())
 	s.redis.HSet(context.Background(), aclKey, req.UserID.String(), req.Permission)
@@ -411,7 +411,7 @@ func (s *AssetService) ShareNote(noteID uuid.UUID, req *model.ShareRequest, shar
 	return nil
 }
 
-func (s *AssetService) RevokeNoteSharing(noteID uuid.UUID, targetUserID uuid.UUID, ownerID uuid.UUID) error {
+func (s *AssetService) RevokeNoteSharing(noteID uuid.UUID, targetUserID uuid.UUID, ownerID uuid.UUID, actedBy string) error {
 	// Check if note exists and user is owner
 	var note model.Note
 	if err := s.db.Where("id = ? AND owner_id = ?", noteID, ownerID).First(&note).Error; err != nil {
@@ -433,7 +433,7 @@ func (s *AssetService) RevokeNoteSharing(noteID uuid.UUID, targetUserID uuid.UUI
 		"targetUserId": targetUserID.String(),
 		"timestamp":   time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), noteID.String(), event)
+	s.publish(noteID.String(), event, actedBy)
 
 	aclKey := fmt.Sprintf("asset:%s:acl", noteID.String())
 	s.redis.HDel(context.Background(), aclKey, targetUserID.String())
@@ -483,7 +483,7 @@ func (s *AssetService) GetTeamAssets(teamID uuid.UUID, token string) (*model.Ass
 }
 
 func (s *AssetService) GetUserAssets(targetUserID uuid.UUID, requestorRole string) (*model.AssetResponse, error) {
-	if requestorRole != "manager" {
+	if !model.HasRole(requestorRole, model.RoleManager) {
 		return nil, fmt.Errorf("access denied")
 	}
 
@@ -573,4 +573,13 @@ func (s *AssetService) noteToResponse(note *model.Note) *model.NoteResponse {
 	}
 
 	return resp
-}
\ No newline at end of file
+}
+
+// publish sends event to asset.changes. Mutations pass actedBy, the admin
+// impersonating the caller if any, so the event is attributed to them too.
+func (s *AssetService) publish(key string, event map[string]interface{}, actedBy string) {
+	if actedBy != "" {
+		event["actedBy"] = actedBy
+	}
+	_ = s.kafka.Publish(context.Background(), key, event)
+}
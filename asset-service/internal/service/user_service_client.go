@@ -17,6 +17,9 @@ import (
 type UserServiceClient struct {
 	baseURL    string
 	httpClient *http.Client
+	// apiKey of the asset-service service account, used for calls made
+	// outside of a user request
+	apiKey string
 }
 
 type GraphQLRequest struct {
@@ -35,6 +38,10 @@ type FetchUsersResponse struct {
 	FetchUsers []model.UserInfo `json:"fetchUsers"`
 }
 
+type FetchUserResponse struct {
+	User *model.UserInfo `json:"user"`
+}
+
 func NewUserServiceClient() *UserServiceClient {
 	_ = godotenv.Load() 
 	baseURL := os.Getenv("USER_SERVICE_URL")
@@ -47,6 +54,7 @@ func NewUserServiceClient() *UserServiceClient {
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		apiKey: os.Getenv("USER_SERVICE_API_KEY"),
 	}
 }
 
@@ -69,6 +77,8 @@ func (c *UserServiceClient) makeGraphQLRequest(query string, variables map[strin
 	req.Header.Set("Content-Type", "application/json")
 	if token != "" {
 		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
 	
 	resp, err := c.httpClient.Do(req)
@@ -89,44 +99,10 @@ func (c *UserServiceClient) makeGraphQLRequest(query string, variables map[strin
 	return &gqlResp, nil
 }
 
-func (c *UserServiceClient) ValidateToken(token string) (*model.UserInfo, error) {
-	query := `
-		query {
-			fetchUsers {
-				userID
-				username
-				email
-				role
-			}
-		}
-	`
-	
-	resp, err := c.makeGraphQLRequest(query, nil, token)
-	if err != nil {
-		return nil, err
-	}
-	
-	var fetchResp FetchUsersResponse
-	dataBytes, err := json.Marshal(resp.Data)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal data: %w", err)
-	}
-	
-	if err := json.Unmarshal(dataBytes, &fetchResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal users: %w", err)
-	}
-	
-	if len(fetchResp.FetchUsers) > 0 {
-		return &fetchResp.FetchUsers[0], nil
-	}
-	
-	return nil, fmt.Errorf("no user found")
-}
-
 func (c *UserServiceClient) GetUserInfo(userID uuid.UUID, token string) (*model.UserInfo, error) {
 	query := `
-		query {
-			fetchUsers {
+		query User($id: ID!) {
+			user(id: $id) {
 				userID
 				username
 				email
@@ -135,29 +111,26 @@ func (c *UserServiceClient) GetUserInfo(userID uuid.UUID, token string) (*model.
 		}
 	`
 	
-	resp, err := c.makeGraphQLRequest(query, nil, token)
+	resp, err := c.makeGraphQLRequest(query, map[string]interface{}{"id": userID.String()}, token)
 	if err != nil {
 		return nil, err
 	}
 	
-	var fetchResp FetchUsersResponse
+	var fetchResp FetchUserResponse
 	dataBytes, err := json.Marshal(resp.Data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal data: %w", err)
 	}
 	
 	if err := json.Unmarshal(dataBytes, &fetchResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal users: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal user: %w", err)
 	}
 	
-	// Find the specific user
-	for _, user := range fetchResp.FetchUsers {
-		if user.UserID == userID.String() {
-			return &user, nil
-		}
+	if fetchResp.User == nil {
+		return nil, fmt.Errorf("user not found")
 	}
 	
-	return nil, fmt.Errorf("user not found")
+	return fetchResp.User, nil
 }
 
 func (c *UserServiceClient) CheckUserExists(userID uuid.UUID, token string) (bool, error) {
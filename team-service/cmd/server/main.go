@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"os"
 	"team-service/config"
 	"team-service/internal/database"
 	"team-service/internal/handler"
 	"team-service/internal/messaging"
+	"team-service/internal/middleware"
 	"team-service/internal/model"
 	"team-service/internal/service"
 	route "team-service/pkg/router"
@@ -43,9 +45,20 @@ func main() {
 		"team.activity",
 	)
 	// Initialize services
-	userServiceClient := service.NewUserServiceClient(getEnv("USER_SERVICE_URL", "http://localhost:8080"))
+	userServiceURL := getEnv("USER_SERVICE_URL", "http://localhost:8080")
+	userServiceClient := service.NewUserServiceClient(userServiceURL, getEnv("USER_SERVICE_API_KEY", ""))
 	teamService := service.NewTeamService(db, userServiceClient, teamProducer, redisClient)
 
+	// Keep member/manager names in sync with user-service
+	userEventConsumer := messaging.NewKafkaConsumer(
+		getEnv("KAFKA_BROKER", "localhost:9092"),
+		"user.events",
+		"team-service",
+	)
+	go userEventConsumer.Consume(context.Background(), teamService.HandleUserEvent, func(err error) {
+		log.WithError(err).Error("Failed to handle user event")
+	})
+
 	// Initialize handler
 	teamHandler := handler.NewTeamHandler(teamService)
 
@@ -65,9 +78,9 @@ func main() {
 		c.Next()
 	})
 
-	// Setup routes
-	jwtSecret := getEnv("JWT_SECRET", "your-secret-key")
-	route.SetupTeamRouter(router, teamHandler, jwtSecret)
+	// Setup routes, verifying tokens with user-service's published keys
+	jwks := middleware.NewJWKSCache(getEnv("JWKS_URL", userServiceURL+"/.well-known/jwks.json"))
+	route.SetupTeamRouter(router, teamHandler, jwks, redisClient, log)
 
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
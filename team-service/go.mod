@@ -2,7 +2,10 @@ module team-service
 
 go 1.24.5
 
-require gorm.io/gorm v1.30.1
+require (
+	gorm.io/driver/postgres v1.6.0
+	gorm.io/gorm v1.30.1
+)
 
 require (
 	github.com/bytedance/sonic v1.11.6 // indirect
@@ -39,7 +42,6 @@ require (
 	golang.org/x/sys v0.31.0 // indirect
 	google.golang.org/protobuf v1.34.1 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
-	gorm.io/driver/postgres v1.6.0 // indirect
 )
 
 require (
@@ -36,7 +36,10 @@ func (h *TeamHandler) CreateTeam(c *gin.Context) {
 	}
 	token := authHeader[7:] // Remove "Bearer " prefix
 
-	team, err := h.teamService.CreateTeam(&req, token)
+	// Set by the auth middleware when an admin is impersonating the user
+	actedBy := c.GetString("actorID")
+
+	team, err := h.teamService.CreateTeam(&req, token, actedBy)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -102,7 +105,7 @@ func (h *TeamHandler) AddMember(c *gin.Context) {
 		return
 	}
 
-	err := h.teamService.AddMember(teamID, &req, currentUserID.(string), token)
+	err := h.teamService.AddMember(teamID, &req, currentUserID.(string), c.GetString("actorID"), token)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -128,7 +131,7 @@ func (h *TeamHandler) RemoveMember(c *gin.Context) {
 		return
 	}
 
-	err := h.teamService.RemoveMember(teamID, memberID, currentUserID.(string))
+	err := h.teamService.RemoveMember(teamID, memberID, currentUserID.(string), c.GetString("actorID"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -166,7 +169,7 @@ func (h *TeamHandler) AddManager(c *gin.Context) {
 		return
 	}
 
-	err := h.teamService.AddManager(teamID, &req, currentUserID.(string), token)
+	err := h.teamService.AddManager(teamID, &req, currentUserID.(string), c.GetString("actorID"), token)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -192,7 +195,7 @@ func (h *TeamHandler) RemoveManager(c *gin.Context) {
 		return
 	}
 
-	err := h.teamService.RemoveManager(teamID, managerID, currentUserID.(string))
+	err := h.teamService.RemoveManager(teamID, managerID, currentUserID.(string), c.GetString("actorID"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
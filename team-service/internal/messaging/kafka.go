@@ -4,9 +4,17 @@ package messaging
 import (
     "context"
     "encoding/json"
+    "errors"
+    "time"
+
     "github.com/segmentio/kafka-go"
 )
 
+const (
+    retryBaseDelay = time.Second
+    retryMaxDelay  = time.Minute
+)
+
 type KafkaProducer struct {
     writer *kafka.Writer
 }
@@ -33,3 +41,88 @@ func (p *KafkaProducer) Publish(ctx context.Context, key string, event interface
         },
     )
 }
+
+type KafkaConsumer struct {
+    reader *kafka.Reader
+}
+
+func NewKafkaConsumer(broker, topic, groupID string) *KafkaConsumer {
+    return &KafkaConsumer{
+        reader: kafka.NewReader(kafka.ReaderConfig{
+            Brokers: []string{broker},
+            GroupID: groupID,
+            Topic:   topic,
+        }),
+    }
+}
+
+// Consume reads messages until ctx is cancelled, passing each value to handle.
+// A message is only committed once handle succeeded: failures are retried
+// with backoff, except for errors wrapped with Permanent. Read, handler and
+// commit errors are reported through onError and don't stop consuming.
+func (c *KafkaConsumer) Consume(ctx context.Context, handle func(value []byte) error, onError func(error)) {
+    defer c.reader.Close()
+
+    for {
+        m, err := c.reader.FetchMessage(ctx)
+        if err != nil {
+            if ctx.Err() != nil {
+                return
+            }
+            onError(err)
+            continue
+        }
+
+        if !handleWithRetry(ctx, m.Value, handle, onError) {
+            return
+        }
+        if err := c.reader.CommitMessages(ctx, m); err != nil {
+            if ctx.Err() != nil {
+                return
+            }
+            onError(err)
+        }
+    }
+}
+
+// handleWithRetry calls handle until it succeeds or fails permanently. It
+// returns false if ctx was cancelled first.
+func handleWithRetry(ctx context.Context, value []byte, handle func(value []byte) error, onError func(error)) bool {
+    delay := retryBaseDelay
+    for {
+        err := handle(value)
+        if err == nil {
+            return true
+        }
+        onError(err)
+        var permanent *permanentError
+        if errors.As(err, &permanent) {
+            return true
+        }
+
+        select {
+        case <-ctx.Done():
+            return false
+        case <-time.After(delay):
+        }
+        delay = min(delay*2, retryMaxDelay)
+    }
+}
+
+// Permanent marks a handler error that retrying can't fix, like a malformed
+// message, so the message is skipped instead.
+func Permanent(err error) error {
+    return &permanentError{err: err}
+}
+
+type permanentError struct {
+    err error
+}
+
+func (e *permanentError) Error() string {
+    return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+    return e.err
+}
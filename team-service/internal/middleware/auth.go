@@ -1,23 +1,44 @@
 package middleware
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
+	"team-service/internal/model"
 
 	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 )
 
 // JWTClaims represents the JWT token claims
 type JWTClaims struct {
 	UserID string `json:"userId"`
 	Role   string `json:"role"`
+	// FamilyID identifies the login session the token belongs to
+	FamilyID string `json:"fid,omitempty"`
+	// Actor is set when an admin impersonates UserID
+	Actor *TokenActor `json:"act,omitempty"`
 	jwt.StandardClaims
 }
 
-// AuthMiddleware validates JWT token and extracts user information
-func AuthMiddleware(secretKey string) gin.HandlerFunc {
+// TokenActor is the act claim of impersonation tokens
+type TokenActor struct {
+	UserID string `json:"sub"`
+}
+
+// Revocation keys must match the ones user-service writes
+const (
+	revokedTokenKeyPrefix   = "auth:revoked:"
+	revokedUserKeyPrefix    = "auth:revoked-user:"
+	revokedSessionKeyPrefix = "auth:revoked-session:"
+)
+
+// AuthMiddleware validates JWT token and extracts user information. Requests
+// of admins impersonating a user are logged with the admin's ID.
+func AuthMiddleware(jwks *JWKSCache, redisClient *redis.Client, log *logrus.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -37,10 +58,11 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 		// Parse the token
 		token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 			// Validate the signing method
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
-			return []byte(secretKey), nil
+			kid, _ := token.Header["kid"].(string)
+			return jwks.Key(kid)
 		})
 
 		if err != nil {
@@ -50,10 +72,32 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 		}
 
 		if claims, ok := token.Claims.(*JWTClaims); ok && token.Valid {
+			// Reject tokens revoked in user-service
+			revoked, err := isRevoked(c.Request.Context(), redisClient, claims)
+			if err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Unable to verify token"})
+				c.Abort()
+				return
+			}
+			if revoked {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				c.Abort()
+				return
+			}
+
 			// Set user information in context
 			c.Set("userID", claims.UserID)
 			c.Set("role", claims.Role)
 			c.Set("token", tokenString)
+			if claims.Actor != nil {
+				c.Set("actorID", claims.Actor.UserID)
+				log.WithFields(logrus.Fields{
+					"actorId": claims.Actor.UserID,
+					"userId":  claims.UserID,
+					"method":  c.Request.Method,
+					"path":    c.Request.URL.Path,
+				}).Info("Impersonated request")
+			}
 			c.Next()
 		} else {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
@@ -63,8 +107,51 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 	}
 }
 
-// RequireRole middleware to check if user has required role
-func RequireRole(roles ...string) gin.HandlerFunc {
+// isRevoked checks whether the token itself (logout) or its session was
+// revoked, or it was issued before all of the user's tokens, or those of the
+// admin impersonating the user, were revoked (deactivation, role change)
+func isRevoked(ctx context.Context, redisClient *redis.Client, claims *JWTClaims) (bool, error) {
+	if claims.Id != "" {
+		n, err := redisClient.Exists(ctx, revokedTokenKeyPrefix+claims.Id).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	if claims.FamilyID != "" {
+		n, err := redisClient.Exists(ctx, revokedSessionKeyPrefix+claims.FamilyID).Result()
+		if err != nil {
+			return false, err
+		}
+		if n > 0 {
+			return true, nil
+		}
+	}
+
+	revoked, err := isUserRevoked(ctx, redisClient, claims.UserID, claims.IssuedAt)
+	if err != nil || revoked || claims.Actor == nil {
+		return revoked, err
+	}
+	return isUserRevoked(ctx, redisClient, claims.Actor.UserID, claims.IssuedAt)
+}
+
+func isUserRevoked(ctx context.Context, redisClient *redis.Client, userID string, issuedAt int64) (bool, error) {
+	revokedAt, err := redisClient.Get(ctx, revokedUserKeyPrefix+userID).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return issuedAt < revokedAt, nil
+}
+
+// RequireRole middleware to check if user has at least minRole
+// (admin > manager > member)
+func RequireRole(minRole string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("role")
 		if !exists {
@@ -73,15 +160,12 @@ func RequireRole(roles ...string) gin.HandlerFunc {
 			return
 		}
 
-		roleStr := userRole.(string)
-		for _, role := range roles {
-			if roleStr == role {
-				c.Next()
-				return
-			}
+		if !model.HasRole(userRole.(string), minRole) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			c.Abort()
+			return
 		}
 
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
-		c.Abort()
+		c.Next()
 	}
 }
\ No newline at end of file
@@ -0,0 +1,28 @@
+package model
+
+// Roles, from most to least privileged. The same hierarchy is used by
+// user-service, team-service and asset-service.
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleMember  = "member"
+)
+
+var roleRank = map[string]int{
+	RoleMember:  1,
+	RoleManager: 2,
+	RoleAdmin:   3,
+}
+
+// IsValidRole reports whether role is one of the known roles.
+func IsValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether role grants at least the permissions of minRole.
+// Unknown roles grant nothing.
+func HasRole(role, minRole string) bool {
+	rank, ok := roleRank[role]
+	return ok && rank >= roleRank[minRole]
+}
@@ -27,10 +27,10 @@ func NewTeamService(db *gorm.DB, userServiceClient *UserServiceClient,
     return &TeamService{db: db, userServiceClient: userServiceClient, kafka: kafka, redis: redis}
 }
 
-func (s *TeamService) CreateTeam(req *model.CreateTeamRequest, token string) (*model.Team, error) {
+func (s *TeamService) CreateTeam(req *model.CreateTeamRequest, token, actedBy string) (*model.Team, error) {
 	// Validate all managers exist and have manager/admin role
 	for _, manager := range req.Managers {
-		_, err := s.userServiceClient.ValidateRole(manager.ManagerID, []string{"manager"}, token)
+		_, err := s.userServiceClient.ValidateRole(manager.ManagerID, model.RoleManager, token)
 		if err != nil {
 			return nil, fmt.Errorf("manager validation failed for %s: %v", manager.ManagerID, err)
 		}
@@ -103,7 +103,7 @@ func (s *TeamService) CreateTeam(req *model.CreateTeamRequest, token string) (*m
 		"performedBy": req.Managers[0].ManagerID, // assume first manager is creator
 		"timestamp":  time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), team.TeamID, event)
+	s.publish(team.TeamID, event, actedBy)
 
 	key := fmt.Sprintf("team:%s:members", team.TeamID)
 	for _, m := range req.Members {
@@ -122,7 +122,7 @@ func (s *TeamService) GetTeamByID(teamID string) (*model.Team, error) {
 	return &team, nil
 }
 
-func (s *TeamService) AddMember(teamID string, req *model.AddMemberRequest, currentUserID string, token string) error {
+func (s *TeamService) AddMember(teamID string, req *model.AddMemberRequest, currentUserID, actedBy string, token string) error {
 	// Check if current user is a manager of this team
 	if !s.isManagerOfTeam(currentUserID, teamID) {
 		return errors.New("only managers can add members")
@@ -167,7 +167,7 @@ func (s *TeamService) AddMember(teamID string, req *model.AddMemberRequest, curr
         "targetUserId": req.MemberID,
         "timestamp":   time.Now().UTC().Format(time.RFC3339),
     }
-    _ = s.kafka.Publish(context.Background(), teamID, event)
+    s.publish(teamID, event, actedBy)
 
     // ✅ Update Redis cache
     key := fmt.Sprintf("team:%s:members", teamID)
@@ -176,7 +176,7 @@ func (s *TeamService) AddMember(teamID string, req *model.AddMemberRequest, curr
     return nil
 }
 
-func (s *TeamService) RemoveMember(teamID, memberID, currentUserID string) error {
+func (s *TeamService) RemoveMember(teamID, memberID, currentUserID, actedBy string) error {
 	// Check if current user is a manager of this team
 	if !s.isManagerOfTeam(currentUserID, teamID) {
 		return errors.New("only managers can remove members")
@@ -197,7 +197,7 @@ func (s *TeamService) RemoveMember(teamID, memberID, currentUserID string) error
 		"targetUserId": memberID,
 		"timestamp":   time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), teamID, event)
+	s.publish(teamID, event, actedBy)
 
 	key := fmt.Sprintf("team:%s:members", teamID)
 	s.redis.SRem(context.Background(), key, memberID)
@@ -205,14 +205,14 @@ func (s *TeamService) RemoveMember(teamID, memberID, currentUserID string) error
 	return nil
 }
 
-func (s *TeamService) AddManager(teamID string, req *model.AddManagerRequest, currentUserID string, token string) error {
+func (s *TeamService) AddManager(teamID string, req *model.AddManagerRequest, currentUserID, actedBy string, token string) error {
 	// Check if current user is the main manager of this team
 	if !s.isMainManagerOfTeam(currentUserID, teamID) {
 		return errors.New("only main manager can add other managers")
 	}
 
 	// Validate manager exists and has manager/admin role
-	_, err := s.userServiceClient.ValidateRole(req.ManagerID, []string{"manager", "admin"}, token)
+	_, err := s.userServiceClient.ValidateRole(req.ManagerID, model.RoleManager, token)
 	if err != nil {
 		return fmt.Errorf("manager validation failed: %v", err)
 	}
@@ -246,12 +246,12 @@ func (s *TeamService) AddManager(teamID string, req *model.AddManagerRequest, cu
 		"targetUserId": req.ManagerID,
 		"timestamp":   time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), teamID, event)
+	s.publish(teamID, event, actedBy)
 
 	return nil
 }
 
-func (s *TeamService) RemoveManager(teamID, managerID, currentUserID string) error {
+func (s *TeamService) RemoveManager(teamID, managerID, currentUserID, actedBy string) error {
 	// Check if current user is the main manager of this team
 	if !s.isMainManagerOfTeam(currentUserID, teamID) {
 		return errors.New("only main manager can remove other managers")
@@ -277,11 +277,20 @@ func (s *TeamService) RemoveManager(teamID, managerID, currentUserID string) err
 		"targetUserId": managerID,
 		"timestamp":   time.Now().UTC().Format(time.RFC3339),
 	}
-	_ = s.kafka.Publish(context.Background(), teamID, event)
+	s.publish(teamID, event, actedBy)
 
 	return nil
 }
 
+// publish sends event to team.activity. Mutations pass actedBy, the admin
+// impersonating the caller if any, so the event is attributed to them too.
+func (s *TeamService) publish(teamID string, event map[string]interface{}, actedBy string) {
+	if actedBy != "" {
+		event["actedBy"] = actedBy
+	}
+	_ = s.kafka.Publish(context.Background(), teamID, event)
+}
+
 func (s *TeamService) isManagerOfTeam(userID, teamID string) bool {
 	var count int64
 	s.db.Model(&model.Manager{}).Where("team_id = ? AND manager_id = ?", teamID, userID).Count(&count)
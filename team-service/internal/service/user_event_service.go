@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"team-service/internal/messaging"
+	"team-service/internal/model"
+)
+
+// UserEvent is a lifecycle event published by user-service on user.events
+type UserEvent struct {
+	EventType string `json:"eventType"`
+	UserID    string `json:"userId"`
+	Username  string `json:"username"`
+}
+
+// HandleUserEvent keeps the denormalized member and manager names in sync with
+// user-service and drops deleted users from their teams.
+func (s *TeamService) HandleUserEvent(value []byte) error {
+	var event UserEvent
+	if err := json.Unmarshal(value, &event); err != nil {
+		return messaging.Permanent(fmt.Errorf("failed to parse user event: %v", err))
+	}
+
+	switch event.EventType {
+	case "USER_DELETED":
+		var members []model.Member
+		if err := s.db.Where("member_id = ?", event.UserID).Find(&members).Error; err != nil {
+			return err
+		}
+		if err := s.db.Where("member_id = ?", event.UserID).Delete(&model.Member{}).Error; err != nil {
+			return err
+		}
+		for _, m := range members {
+			key := fmt.Sprintf("team:%s:members", m.TeamID)
+			s.redis.SRem(context.Background(), key, event.UserID)
+		}
+
+		// The main manager is kept so the team is never left without an owner
+		return s.db.Where("manager_id = ? AND is_main = ?", event.UserID, false).Delete(&model.Manager{}).Error
+	}
+
+	// Every other event carries the current username too, and a rename may
+	// come along with a role or status change that is published instead of
+	// USER_UPDATED
+	if event.Username == "" {
+		return nil
+	}
+	if err := s.db.Model(&model.Member{}).Where("member_id = ?", event.UserID).
+		Update("member_name", event.Username).Error; err != nil {
+		return err
+	}
+	return s.db.Model(&model.Manager{}).Where("manager_id = ?", event.UserID).
+		Update("manager_name", event.Username).Error
+}
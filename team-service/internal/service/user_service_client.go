@@ -6,16 +6,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"team-service/internal/model"
 	"time"
 )
 
 type UserServiceClient struct {
 	BaseURL string
 	Client  *http.Client
+	// APIKey of the team-service service account, used for calls made
+	// outside of a user request
+	APIKey string
 }
 
 type GraphQLRequest struct {
-	Query string `json:"query"`
+	Query     string                 `json:"query"`
+	Variables map[string]interface{} `json:"variables,omitempty"`
 }
 
 type UserData struct {
@@ -34,15 +39,34 @@ type FetchUsersResponse struct {
 	} `json:"errors"`
 }
 
-func NewUserServiceClient(baseURL string) *UserServiceClient {
+type FetchUserResponse struct {
+	Data struct {
+		User *UserData `json:"user"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func NewUserServiceClient(baseURL string, apiKey string) *UserServiceClient {
 	return &UserServiceClient{
 		BaseURL: baseURL,
 		Client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		APIKey: apiKey,
 	}
 }
 
+// authorization returns the Authorization header for a call on behalf of the
+// user holding token, or of the service account when there is no user.
+func (u *UserServiceClient) authorization(token string) string {
+	if token == "" {
+		return "Bearer " + u.APIKey
+	}
+	return "Bearer " + token
+}
+
 func (u *UserServiceClient) FetchUsers(token string) ([]UserData, error) {
 	query := `
 		query {
@@ -67,7 +91,7 @@ func (u *UserServiceClient) FetchUsers(token string) ([]UserData, error) {
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Authorization", u.authorization(token))
 
 	resp, err := u.Client.Do(req)
 	if err != nil {
@@ -98,34 +122,83 @@ func (u *UserServiceClient) FetchUsers(token string) ([]UserData, error) {
 }
 
 
+// FetchUser looks up a single user by ID, returning nil if it doesn't exist
+func (u *UserServiceClient) FetchUser(userID string, token string) (*UserData, error) {
+	query := `
+		query User($id: ID!) {
+			user(id: $id) {
+				userID
+				username
+				email
+				role
+			}
+		}
+	`
+
+	reqBody := GraphQLRequest{Query: query, Variables: map[string]interface{}{"id": userID}}
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", u.BaseURL+"/query", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %v", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", u.authorization(token))
+
+	resp, err := u.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("user service error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var response FetchUserResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %v. raw body: %s", err, string(body))
+	}
+
+	if len(response.Errors) > 0 {
+		return nil, fmt.Errorf("user service error: %s", response.Errors[0].Message)
+	}
+
+	return response.Data.User, nil
+}
+
 func (u *UserServiceClient) ValidateUser(userID string, token string) (*UserData, error) {
-	users, err := u.FetchUsers(token)
+	user, err := u.FetchUser(userID, token)
 	if err != nil {
 		return nil, err
 	}
-
-	for _, user := range users {
-		if user.UserID == userID {
-			return &user, nil
-		}
+	if user == nil {
+		return nil, fmt.Errorf("user not found")
 	}
 
-	return nil, fmt.Errorf("user not found")
+	return user, nil
 }
 
-func (u *UserServiceClient) ValidateRole(userID string, expectedRoles []string, token string) (*UserData, error) {
+// ValidateRole checks that the user exists and has at least minRole
+// (admin > manager > member).
+func (u *UserServiceClient) ValidateRole(userID string, minRole string, token string) (*UserData, error) {
 	user, err := u.ValidateUser(userID, token)
-	fmt.Println("user", user)
-	fmt.Println("err", err)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, role := range expectedRoles {
-		if user.Role == role {
-			return user, nil
-		}
+	if !model.HasRole(user.Role, minRole) {
+		return nil, fmt.Errorf("user does not have required role")
 	}
 
-	return nil, fmt.Errorf("user does not have required role")
+	return user, nil
 }
\ No newline at end of file
@@ -3,21 +3,24 @@ package router
 import (
 	"team-service/internal/handler"   
 	"team-service/internal/middleware" 
+	"team-service/internal/model"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/sirupsen/logrus"
 )
 
 // SetupTeamRoutes sets up all team-related routes
-func SetupTeamRouter(router *gin.Engine, teamHandler *handler.TeamHandler, jwtSecret string) {
+func SetupTeamRouter(router *gin.Engine, teamHandler *handler.TeamHandler, jwks *middleware.JWKSCache, redisClient *redis.Client, log *logrus.Logger) {
 	// Apply auth middleware to all team routes
 	api := router.Group("/api/v1")
-	api.Use(middleware.AuthMiddleware(jwtSecret))
+	api.Use(middleware.AuthMiddleware(jwks, redisClient, log))
 
 	// Team routes
 	teams := api.Group("/teams")
 	{
 		// Create team - requires manager or admin role
-		teams.POST("", middleware.RequireRole("manager", "admin"), teamHandler.CreateTeam)
+		teams.POST("", middleware.RequireRole(model.RoleManager), teamHandler.CreateTeam)
 		
 		// Get all teams - any authenticated user
 		teams.GET("", teamHandler.GetAllTeams)
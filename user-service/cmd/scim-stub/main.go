@@ -0,0 +1,139 @@
+// Command scim-stub plays the part of an identity provider against the SCIM
+// endpoints, to try provisioning locally:
+//
+//	SCIM_TOKEN=sa_... go run ./cmd/scim-stub [email]
+//
+// It creates a user, looks it up by filter, patches it, moves it through the
+// manager group, deactivates and reactivates it and finally deletes it,
+// printing every exchange. SCIM_URL defaults to http://localhost:8080/scim/v2.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// do sends a request and decodes the response into out, failing unless the
+// status is want.
+func (c *client) do(method, path string, body interface{}, want int, out interface{}) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/scim+json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%s %s -> %d\n", method, path, resp.StatusCode)
+	if len(data) > 0 {
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, data, "  ", "  ") == nil {
+			fmt.Printf("  %s\n", pretty.String())
+		}
+	}
+	if resp.StatusCode != want {
+		log.Fatalf("expected status %d", want)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func patch(ops ...map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"schemas":    []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+		"Operations": ops,
+	}
+}
+
+func main() {
+	c := &client{
+		baseURL: os.Getenv("SCIM_URL"),
+		token:   os.Getenv("SCIM_TOKEN"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+	if c.baseURL == "" {
+		c.baseURL = "http://localhost:8080/scim/v2"
+	}
+	if c.token == "" {
+		log.Fatal("SCIM_TOKEN must be the API key of a service account with the users:provision scope")
+	}
+	email := fmt.Sprintf("scim.stub.%d@example.com", time.Now().Unix())
+	if len(os.Args) > 1 {
+		email = os.Args[1]
+	}
+
+	c.do(http.MethodGet, "/ServiceProviderConfig", nil, http.StatusOK, nil)
+
+	var user struct {
+		ID string `json:"id"`
+	}
+	c.do(http.MethodPost, "/Users", map[string]interface{}{
+		"schemas":     []string{"urn:ietf:params:scim:schemas:core:2.0:User"},
+		"externalId":  "stub-" + email,
+		"userName":    email,
+		"displayName": "SCIM Stub",
+		"emails":      []map[string]interface{}{{"value": email, "type": "work", "primary": true}},
+		"active":      true,
+	}, http.StatusCreated, &user)
+
+	filter := url.QueryEscape(fmt.Sprintf(`userName eq "%s"`, email))
+	c.do(http.MethodGet, "/Users?filter="+filter, nil, http.StatusOK, nil)
+
+	c.do(http.MethodPatch, "/Users/"+user.ID, patch(
+		map[string]interface{}{"op": "replace", "path": "title", "value": "Engineer"},
+		map[string]interface{}{"op": "replace", "value": map[string]interface{}{"locale": "en-US", "timezone": "Europe/Paris"}},
+	), http.StatusOK, nil)
+
+	c.do(http.MethodPatch, "/Groups/manager", patch(
+		map[string]interface{}{"op": "add", "path": "members", "value": []map[string]string{{"value": user.ID}}},
+	), http.StatusOK, nil)
+	c.do(http.MethodPatch, "/Groups/manager", patch(
+		map[string]interface{}{"op": "remove", "path": fmt.Sprintf(`members[value eq "%s"]`, user.ID)},
+	), http.StatusOK, nil)
+
+	c.do(http.MethodPatch, "/Users/"+user.ID, patch(
+		map[string]interface{}{"op": "replace", "path": "active", "value": "False"},
+	), http.StatusOK, nil)
+	c.do(http.MethodPatch, "/Users/"+user.ID, patch(
+		map[string]interface{}{"op": "replace", "path": "active", "value": true},
+	), http.StatusOK, nil)
+
+	c.do(http.MethodDelete, "/Users/"+user.ID, nil, http.StatusNoContent, nil)
+	c.do(http.MethodGet, "/Users/"+user.ID, nil, http.StatusNotFound, nil)
+
+	fmt.Println("provisioning scenario passed")
+}
@@ -1,17 +1,35 @@
 package main
 
 import (
+	"context"
 	"log/slog"
+	"net/http"
+	"net/url"
 	"os"
+	"time"
 	"user-service/config"
 	"user-service/graph/generated"
+	"user-service/graph/limits"
 	"user-service/graph/resolver"
+	"user-service/internal/audit"
 	"user-service/internal/auth"
 	"user-service/internal/database"
+	"user-service/internal/events"
+	"user-service/internal/loader"
+	"user-service/internal/mail"
+	"user-service/internal/messaging"
+	"user-service/internal/password"
+	"user-service/internal/pubsub"
+	"user-service/internal/scim"
 
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 )
 
 func main() {
@@ -19,6 +37,7 @@ func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
+	slog.SetDefault(logger)
 
 	// Load config
 	cfg, err := config.LoadConfig()
@@ -37,19 +56,146 @@ func main() {
 	database.Migrate(db)
 	logger.Info("Database connected and migrated", "service", "user-service")
 
-	// Init JWT secrets
-	auth.Init(cfg.JWT.Secret, cfg.JWT.RefreshSecret)
-	logger.Info("JWT secrets initialized", "service", "user-service")
+	// Init JWT signing keys
+	signingKeys, err := auth.LoadKeySet(cfg.JWT.KeysDir, cfg.JWT.SigningKeyID, cfg.JWT.AutoGenerate)
+	if err != nil {
+		logger.Error("Failed to load JWT signing keys", "error", err)
+		os.Exit(1)
+	}
+	auth.Init(signingKeys, cfg.JWT.RefreshSecret)
+	logger.Info("JWT signing keys loaded", "kid", signingKeys.ActiveKID(), "service", "user-service")
+
+	// Token revocation list (logout)
+	redisClient := messaging.NewRedisClient(cfg.Redis.Addr)
+	revocations := auth.NewRevocationStore(redisClient)
+
+	// API keys of service accounts
+	apiKeys := auth.NewAPIKeyStore(db)
+
+	// Activity of user sessions
+	sessions := auth.NewSessionStore(db, redisClient)
+
+	// Brute-force protection on login
+	loginLimiter := auth.NewLoginLimiter(redisClient, auth.LoginLimiterConfig{
+		MaxFailures:     cfg.Login.MaxFailures,
+		LockoutDuration: cfg.Login.LockoutDuration,
+		IPMaxFailures:   cfg.Login.IPMaxFailures,
+		Window:          cfg.Login.Window,
+		BaseDelay:       cfg.Login.BaseDelay,
+		MaxDelay:        cfg.Login.MaxDelay,
+	})
+
+	// Passwordless login links
+	magicLinkLimiter := auth.NewMagicLinkLimiter(redisClient, cfg.MagicLink.MaxRequests, cfg.MagicLink.Window)
+
+	// User lifecycle events
+	userProducer := messaging.NewKafkaProducer(cfg.Kafka.Broker, "user.events")
+
+	// userChanged subscriptions, relayed between replicas through Redis
+	userChanges := pubsub.NewUserChanges(redisClient)
+	go userChanges.Run(context.Background())
+	userEvents := events.NewUserEvents(userProducer, userChanges)
+
+	// Authentication audit trail, with alerts for logins from new devices
+	notificationProducer := messaging.NewKafkaProducer(cfg.Kafka.Broker, "user.notifications")
+	auditTrail := audit.NewTrail(db, notificationProducer)
+
+	// Outgoing mail (password reset links)
+	mailer, err := mail.NewMailer(cfg.Mail)
+	if err != nil {
+		logger.Error("Failed to init mailer", "error", err)
+		os.Exit(1)
+	}
+
+	// Password policy (createUser, changePassword, resetPassword)
+	passwords, err := password.NewPolicy(cfg.Password)
+	if err != nil {
+		logger.Error("Failed to load password policy", "error", err)
+		os.Exit(1)
+	}
+
+	// First admin
+	created, err := database.BootstrapAdmin(db, cfg.Admin, passwords)
+	if err != nil {
+		logger.Error("Failed to bootstrap admin", "error", err)
+		os.Exit(1)
+	}
+	if created {
+		logger.Info("Bootstrap admin ready", "email", cfg.Admin.Email, "service", "user-service")
+	}
+
+	// Two-factor authentication
+	totp, err := auth.NewTOTP(cfg.MFA.Issuer, cfg.MFA.EncryptionKey)
+	if err != nil {
+		logger.Error("Failed to init TOTP", "error", err)
+		os.Exit(1)
+	}
 
 	// GraphQL server
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
-		Resolvers: &resolver.Resolver{DB: db},
+	srv := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers: &resolver.Resolver{
+			DB:                   db,
+			Revocations:          revocations,
+			Events:               userEvents,
+			Mailer:               mailer,
+			FrontendURL:          cfg.Server.FrontendURL,
+			Passwords:            passwords,
+			LoginLimiter:         loginLimiter,
+			RequireVerifiedEmail: cfg.Login.RequireVerifiedEmail,
+			TOTP:                 totp,
+			MFARequiredRoles:     cfg.MFA.RequiredRoles,
+			MagicLinkTTL:         cfg.MagicLink.TTL,
+			MagicLinkLimiter:     magicLinkLimiter,
+			UserChanges:          userChanges,
+			Audit:                auditTrail,
+		},
+		Complexity: resolver.Complexity(),
 	}))
 
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		InitFunc:              auth.WebsocketInit(revocations, apiKeys, sessions),
+		Upgrader: websocket.Upgrader{
+			CheckOrigin: allowOrigin(cfg.Server.FrontendURL),
+		},
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+	srv.SetQueryCache(lru.New[*ast.QueryDocument](1000))
+	srv.Use(extension.Introspection{})
+	// Admins acting as another user are logged with every operation
+	srv.AroundOperations(auth.LogImpersonation)
+
+	// Query limits: clients can register queries by hash (APQ), and every
+	// operation is bounded in depth and complexity and charged to a budget
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: limits.NewPersistedQueryCache(redisClient, cfg.GraphQL.PersistedTTL, cfg.GraphQL.PersistedMaxSize),
+	})
+	srv.Use(limits.DepthLimit{MaxDepth: cfg.GraphQL.MaxDepth})
+	srv.Use(extension.FixedComplexityLimit(cfg.GraphQL.ComplexityLimit))
+	srv.Use(&limits.QueryBudget{
+		Redis:  redisClient,
+		Limit:  cfg.GraphQL.QueryBudget,
+		Window: cfg.GraphQL.BudgetWindow,
+	})
+
 	// Gin router
 	r := gin.Default()
+	// Without trusted proxies X-Forwarded-For is ignored, so the client IP used
+	// by login protection can't be spoofed
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		logger.Error("Invalid trusted proxies", "error", err)
+		os.Exit(1)
+	}
 	r.GET("/graphql", gin.WrapH(playground.Handler("GraphQL Playground", "/query")))
-	r.POST("/query", auth.AuthMiddleware(), gin.WrapH(srv))
+	r.GET("/.well-known/jwks.json", auth.JWKSHandler(signingKeys))
+	r.POST("/query", auth.AuthMiddleware(revocations, apiKeys, sessions), loader.Middleware(db), gin.WrapH(srv))
+	// Websocket upgrade for subscriptions
+	r.GET("/query", auth.AuthMiddleware(revocations, apiKeys, sessions), loader.Middleware(db), gin.WrapH(srv))
+	// SCIM provisioning by identity providers
+	scim.NewHandler(db, revocations, apiKeys, passwords, userEvents, auditTrail).Register(r)
 
 	// Start server
 	logger.Info("Server started", "url", "http://localhost:8080", "service", "user-service")
@@ -58,3 +204,16 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// allowOrigin accepts websocket upgrades from the frontend and from pages
+// served by this service itself, like the playground.
+func allowOrigin(frontendURL string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" || origin == frontendURL {
+			return true
+		}
+		u, err := url.Parse(origin)
+		return err == nil && u.Host == r.Host
+	}
+}
@@ -1,13 +1,26 @@
 package config
 
+import "time"
+
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
+	Server    ServerConfig
+	Database  DatabaseConfig
+	Redis     RedisConfig
+	Kafka     KafkaConfig
+	JWT       JWTConfig
+	Mail      MailConfig
+	Password  PasswordConfig
+	Login     LoginConfig
+	MFA       MFAConfig
+	MagicLink MagicLinkConfig
+	Admin     AdminConfig
+	GraphQL   GraphQLConfig
 }
 
 type ServerConfig struct {
-	Port string
+	Port           string
+	FrontendURL    string
+	TrustedProxies []string
 }
 
 type DatabaseConfig struct {
@@ -19,7 +32,86 @@ type DatabaseConfig struct {
 	SSLMode  string
 }
 
+type RedisConfig struct {
+	Addr string
+}
+
+type KafkaConfig struct {
+	Broker string
+}
+
+type MailConfig struct {
+	Driver       string
+	From         string
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	OutboxDir    string
+}
+
 type JWTConfig struct {
-	Secret string
+	KeysDir       string
+	SigningKeyID  string
 	RefreshSecret string
+	// AutoGenerate creates a signing key when KeysDir has none. Only for
+	// local development.
+	AutoGenerate bool
+}
+
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	HistorySize   int
+	DenyListFile  string
+	// Argon2 parameters of new hashes; Argon2Memory is in KiB
+	Argon2Memory      int
+	Argon2Iterations  int
+	Argon2Parallelism int
+}
+
+type LoginConfig struct {
+	MaxFailures     int
+	LockoutDuration time.Duration
+	IPMaxFailures   int
+	Window          time.Duration
+	BaseDelay       time.Duration
+	MaxDelay        time.Duration
+	// RequireVerifiedEmail refuses logins until the email address is verified
+	RequireVerifiedEmail bool
+}
+
+type MFAConfig struct {
+	Issuer        string
+	EncryptionKey string
+	RequiredRoles []string
+}
+
+// MagicLinkConfig bounds passwordless login links. Which roles may use them is
+// switched by admins at runtime.
+type MagicLinkConfig struct {
+	TTL time.Duration
+	// MaxRequests links can be requested per email within Window
+	MaxRequests int
+	Window      time.Duration
+}
+
+// AdminConfig is the first admin, created on startup while there is none.
+type AdminConfig struct {
+	Email    string
+	Username string
+	Password string
+}
+
+type GraphQLConfig struct {
+	ComplexityLimit int
+	MaxDepth        int
+	QueryBudget     int
+	BudgetWindow    time.Duration
+	PersistedTTL    time.Duration
+	// PersistedMaxSize is the largest query in bytes that can be registered.
+	PersistedMaxSize int
 }
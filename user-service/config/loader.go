@@ -1,7 +1,11 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -12,7 +16,9 @@ func LoadConfig() (*Config, error) {
 	cfg := &Config{}
 
 	cfg.Server = ServerConfig{
-		Port: getEnv("SERVER_PORT", "8080"),
+		Port:           getEnv("SERVER_PORT", "8080"),
+		FrontendURL:    getEnv("FRONTEND_URL", "http://localhost:3000"),
+		TrustedProxies: getEnvList("TRUSTED_PROXIES", ""),
 	}
 
 	cfg.Database = DatabaseConfig{
@@ -24,9 +30,91 @@ func LoadConfig() (*Config, error) {
 		SSLMode:  getEnv("DB_SSLMODE", "disable"),
 	}
 
+	cfg.Redis = RedisConfig{
+		Addr: getEnv("REDIS_ADDR", "localhost:6379"),
+	}
+
+	cfg.Kafka = KafkaConfig{
+		Broker: getEnv("KAFKA_BROKER", "localhost:9092"),
+	}
+
 	cfg.JWT = JWTConfig{
-		Secret: getEnv("JWT_SECRET", "super-secret-key"),
-		RefreshSecret: getEnv("JWT_REFRESH_SECRET", "super-secret-key"),
+		KeysDir:       getEnv("JWT_KEYS_DIR", "keys"),
+		SigningKeyID:  getEnv("JWT_SIGNING_KID", ""),
+		RefreshSecret: getEnv("JWT_REFRESH_SECRET", ""),
+		AutoGenerate:  getEnvBool("JWT_KEY_AUTOGENERATE", false),
+	}
+	// The secret signs refresh, 2FA challenge, invitation and magic-link
+	// tokens, so a known default would let anyone forge them
+	if cfg.JWT.RefreshSecret == "" {
+		return nil, errors.New("JWT_REFRESH_SECRET is required")
+	}
+
+	cfg.Mail = MailConfig{
+		Driver:       getEnv("MAIL_DRIVER", "file"),
+		From:         getEnv("MAIL_FROM", "no-reply@example.com"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		OutboxDir:    getEnv("MAIL_OUTBOX_DIR", "outbox"),
+	}
+
+	cfg.Password = PasswordConfig{
+		MinLength:     getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		RequireUpper:  getEnvBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:  getEnvBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:  getEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol: getEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+		HistorySize:   getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		DenyListFile:  getEnv("PASSWORD_DENYLIST_FILE", ""),
+
+		Argon2Memory:      getEnvInt("PASSWORD_ARGON2_MEMORY", 64*1024),
+		Argon2Iterations:  getEnvInt("PASSWORD_ARGON2_ITERATIONS", 3),
+		Argon2Parallelism: getEnvInt("PASSWORD_ARGON2_PARALLELISM", 2),
+	}
+
+	cfg.Login = LoginConfig{
+		MaxFailures:          getEnvInt("LOGIN_MAX_FAILURES", 5),
+		LockoutDuration:      getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		IPMaxFailures:        getEnvInt("LOGIN_IP_MAX_FAILURES", 20),
+		Window:               getEnvDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute),
+		BaseDelay:            getEnvDuration("LOGIN_BASE_DELAY", 500*time.Millisecond),
+		MaxDelay:             getEnvDuration("LOGIN_MAX_DELAY", 5*time.Second),
+		RequireVerifiedEmail: getEnvBool("LOGIN_REQUIRE_VERIFIED_EMAIL", false),
+	}
+
+	cfg.MFA = MFAConfig{
+		Issuer:        getEnv("MFA_ISSUER", "user-service"),
+		EncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+		// Set to "none" so no role is required to use 2FA
+		RequiredRoles: getEnvList("MFA_REQUIRED_ROLES", "admin,manager"),
+	}
+	// A default key would seal every deployment's TOTP secrets with the same
+	// known value; local development sets one in .env
+	if cfg.MFA.EncryptionKey == "" {
+		return nil, errors.New("MFA_ENCRYPTION_KEY is required")
+	}
+
+	cfg.MagicLink = MagicLinkConfig{
+		TTL:         getEnvDuration("MAGIC_LINK_TTL", 15*time.Minute),
+		MaxRequests: getEnvInt("MAGIC_LINK_MAX_REQUESTS", 3),
+		Window:      getEnvDuration("MAGIC_LINK_WINDOW", time.Hour),
+	}
+
+	cfg.Admin = AdminConfig{
+		Email:    getEnv("BOOTSTRAP_ADMIN_EMAIL", ""),
+		Username: getEnv("BOOTSTRAP_ADMIN_USERNAME", "admin"),
+		Password: getEnv("BOOTSTRAP_ADMIN_PASSWORD", ""),
+	}
+
+	cfg.GraphQL = GraphQLConfig{
+		ComplexityLimit:  getEnvInt("GRAPHQL_COMPLEXITY_LIMIT", 2000),
+		MaxDepth:         getEnvInt("GRAPHQL_MAX_DEPTH", 10),
+		QueryBudget:      getEnvInt("GRAPHQL_QUERY_BUDGET", 10000),
+		BudgetWindow:     getEnvDuration("GRAPHQL_BUDGET_WINDOW", time.Minute),
+		PersistedTTL:     getEnvDuration("GRAPHQL_APQ_TTL", 24*time.Hour),
+		PersistedMaxSize: getEnvInt("GRAPHQL_APQ_MAX_SIZE", 16384),
 	}
 
 	return cfg, nil
@@ -37,4 +125,36 @@ func getEnv(key, fallback string) string {
 		return val
 	}
 	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if val, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return val
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if val, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return val
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if val, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return val
+	}
+	return fallback
+}
+
+// getEnvList splits a comma separated variable. An empty fallback gives nil.
+func getEnvList(key, fallback string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
 }
\ No newline at end of file
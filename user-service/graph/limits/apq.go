@@ -0,0 +1,60 @@
+package limits
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"user-service/internal/auth"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/redis/go-redis/v9"
+)
+
+const persistedQueryKeyPrefix = "gql:apq:"
+
+// PersistedQueryCache stores automatic persisted queries in Redis, so a hash
+// registered through one instance can be used on any other. Only
+// authenticated callers can register queries, of at most maxSize bytes, so
+// anonymous clients can't fill Redis; anyone can use registered ones.
+type PersistedQueryCache struct {
+	redis   *redis.Client
+	ttl     time.Duration
+	maxSize int
+}
+
+var _ graphql.Cache[string] = (*PersistedQueryCache)(nil)
+
+func NewPersistedQueryCache(client *redis.Client, ttl time.Duration, maxSize int) *PersistedQueryCache {
+	return &PersistedQueryCache{redis: client, ttl: ttl, maxSize: maxSize}
+}
+
+func (c *PersistedQueryCache) Get(ctx context.Context, hash string) (string, bool) {
+	query, err := c.redis.Get(ctx, persistedQueryKeyPrefix+hash).Result()
+	if err != nil {
+		if err != redis.Nil {
+			slog.Warn("Failed to read persisted query", "hash", hash, "error", err)
+		}
+		return "", false
+	}
+	// Queries in use stay cached
+	c.redis.Expire(ctx, persistedQueryKeyPrefix+hash, c.ttl)
+	return query, true
+}
+
+func (c *PersistedQueryCache) Add(ctx context.Context, hash string, query string) {
+	if len(query) > c.maxSize || !isAuthenticated(ctx) {
+		return
+	}
+	if err := c.redis.Set(ctx, persistedQueryKeyPrefix+hash, query, c.ttl).Err(); err != nil {
+		slog.Warn("Failed to store persisted query", "hash", hash, "error", err)
+	}
+}
+
+func isAuthenticated(ctx context.Context) bool {
+	if _, err := auth.GetUserIDFromContext(ctx); err == nil {
+		return true
+	}
+	_, err := auth.GetServicePrincipalFromContext(ctx)
+	return err == nil
+}
@@ -0,0 +1,82 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"user-service/internal/auth"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/redis/go-redis/v9"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	queryBudgetKeyPrefix = "gql:budget:"
+	errBudgetExceeded    = "RATE_LIMITED"
+)
+
+// QueryBudget charges the complexity of every operation to its caller, the
+// user, service account or for anonymous requests the client IP, and rejects
+// operations once Limit is spent within the current Window. Counters live in
+// Redis so all instances share them.
+type QueryBudget struct {
+	Redis  *redis.Client
+	Limit  int
+	Window time.Duration
+
+	schema graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = &QueryBudget{}
+
+func (b *QueryBudget) ExtensionName() string {
+	return "QueryBudget"
+}
+
+func (b *QueryBudget) Validate(schema graphql.ExecutableSchema) error {
+	if b.Limit <= 0 || b.Window <= 0 {
+		return fmt.Errorf("query budget needs a positive limit and window")
+	}
+	b.schema = schema
+	return nil
+}
+
+func (b *QueryBudget) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	caller := "ip:" + auth.GetClientIPFromContext(ctx)
+	if userID, err := auth.GetUserIDFromContext(ctx); err == nil {
+		caller = "user:" + userID
+	} else if principal, err := auth.GetServicePrincipalFromContext(ctx); err == nil {
+		caller = "service:" + principal.ID
+	}
+
+	cost := complexity.Calculate(ctx, b.schema, opCtx.Operation, opCtx.Variables)
+	window := time.Now().UnixNano() / int64(b.Window)
+	key := fmt.Sprintf("%s%s:%d", queryBudgetKeyPrefix, caller, window)
+
+	pipe := b.Redis.TxPipeline()
+	spent := pipe.IncrBy(ctx, key, int64(cost))
+	pipe.Expire(ctx, key, b.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		// An outage of the budget store shouldn't take the API down with it
+		slog.Warn("Failed to charge query budget", "caller", caller, "error", err)
+		return nil
+	}
+
+	if spent.Val() > int64(b.Limit) {
+		resetAt := time.Unix(0, (window+1)*int64(b.Window))
+		err := gqlerror.Errorf("query budget of %d per %s exceeded", b.Limit, b.Window)
+		err.Extensions = map[string]interface{}{
+			"code":       errBudgetExceeded,
+			"retryAfter": int(time.Until(resetAt).Seconds()) + 1,
+		}
+		return err
+	}
+	return nil
+}
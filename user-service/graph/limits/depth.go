@@ -0,0 +1,72 @@
+package limits
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// DepthLimit rejects operations whose selections nest deeper than MaxDepth.
+// Introspection fields don't count, so tooling keeps working.
+type DepthLimit struct {
+	MaxDepth int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit{}
+
+func (d DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (d DepthLimit) Validate(schema graphql.ExecutableSchema) error {
+	if d.MaxDepth <= 0 {
+		return fmt.Errorf("max depth must be positive, got %d", d.MaxDepth)
+	}
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	depth := selectionDepth(opCtx.Operation.SelectionSet, map[string]bool{})
+	if depth > d.MaxDepth {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.MaxDepth)
+		err.Extensions = map[string]interface{}{"code": errDepthLimit}
+		return err
+	}
+	return nil
+}
+
+// selectionDepth returns how deep fields nest in set. visiting guards against
+// fragment cycles, which validation should already have rejected.
+func selectionDepth(set ast.SelectionSet, visiting map[string]bool) int {
+	depth := 0
+	for _, selection := range set {
+		var d int
+		switch s := selection.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			d = 1 + selectionDepth(s.SelectionSet, visiting)
+		case *ast.InlineFragment:
+			d = selectionDepth(s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			if s.Definition == nil || visiting[s.Name] {
+				continue
+			}
+			visiting[s.Name] = true
+			d = selectionDepth(s.Definition.SelectionSet, visiting)
+			delete(visiting, s.Name)
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
@@ -3,12 +3,30 @@
 package model
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
 	"user-service/internal/model"
 )
 
+type APIKey struct {
+	ID         string     `json:"id"`
+	Prefix     string     `json:"prefix"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
 type AuthPayload struct {
-	Token string      `json:"token"`
-	User  *model.User `json:"user"`
+	Token                 *string     `json:"token,omitempty"`
+	RefreshToken          *string     `json:"refreshToken,omitempty"`
+	User                  *model.User `json:"user"`
+	MfaRequired           bool        `json:"mfaRequired"`
+	MfaEnrollmentRequired bool        `json:"mfaEnrollmentRequired"`
+	MfaToken              *string     `json:"mfaToken,omitempty"`
 }
 
 type CreateUserInput struct {
@@ -18,6 +36,12 @@ type CreateUserInput struct {
 	Role     string `json:"role"`
 }
 
+type ImpersonationPayload struct {
+	Token     string      `json:"token"`
+	ExpiresAt time.Time   `json:"expiresAt"`
+	User      *model.User `json:"user"`
+}
+
 type LoginInput struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
@@ -26,5 +50,156 @@ type LoginInput struct {
 type Mutation struct {
 }
 
+type PageInfo struct {
+	EndCursor   *string `json:"endCursor,omitempty"`
+	HasNextPage bool    `json:"hasNextPage"`
+}
+
+type Preference struct {
+	Key       string         `json:"key"`
+	Type      PreferenceType `json:"type"`
+	Value     any            `json:"value"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
 type Query struct {
 }
+
+type ServiceAccount struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Disabled  bool      `json:"disabled"`
+	Keys      []*APIKey `json:"keys"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ServiceAccountCredentials struct {
+	ServiceAccount *ServiceAccount `json:"serviceAccount"`
+	APIKey         string          `json:"apiKey"`
+}
+
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IPAddress  string    `json:"ipAddress"`
+	Current    bool      `json:"current"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
+type Subscription struct {
+}
+
+type TokenIntrospection struct {
+	Active    bool        `json:"active"`
+	User      *model.User `json:"user,omitempty"`
+	Role      *string     `json:"role,omitempty"`
+	ExpiresAt *time.Time  `json:"expiresAt,omitempty"`
+}
+
+type TotpConfirmation struct {
+	RecoveryCodes []string     `json:"recoveryCodes"`
+	Auth          *AuthPayload `json:"auth,omitempty"`
+}
+
+type TotpEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauthUri"`
+}
+
+type UpdateProfileInput struct {
+	DisplayName *string `json:"displayName,omitempty"`
+	AvatarURL   *string `json:"avatarUrl,omitempty"`
+	Timezone    *string `json:"timezone,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+	JobTitle    *string `json:"jobTitle,omitempty"`
+}
+
+type UpdateUserInput struct {
+	Username *string `json:"username,omitempty"`
+	Email    *string `json:"email,omitempty"`
+}
+
+type UserChangeFilter struct {
+	Types  []string `json:"types,omitempty"`
+	UserID *string  `json:"userID,omitempty"`
+	Role   *string  `json:"role,omitempty"`
+}
+
+type UserConnection struct {
+	Edges    []*UserEdge `json:"edges"`
+	PageInfo *PageInfo   `json:"pageInfo"`
+}
+
+type UserEdge struct {
+	Cursor string      `json:"cursor"`
+	Node   *model.User `json:"node"`
+}
+
+type UserFilter struct {
+	Role         *string    `json:"role,omitempty"`
+	Email        *string    `json:"email,omitempty"`
+	CreatedAfter *time.Time `json:"createdAfter,omitempty"`
+}
+
+type PreferenceType string
+
+const (
+	PreferenceTypeString  PreferenceType = "STRING"
+	PreferenceTypeBoolean PreferenceType = "BOOLEAN"
+	PreferenceTypeInt     PreferenceType = "INT"
+	PreferenceTypeFloat   PreferenceType = "FLOAT"
+	PreferenceTypeJSON    PreferenceType = "JSON"
+)
+
+var AllPreferenceType = []PreferenceType{
+	PreferenceTypeString,
+	PreferenceTypeBoolean,
+	PreferenceTypeInt,
+	PreferenceTypeFloat,
+	PreferenceTypeJSON,
+}
+
+func (e PreferenceType) IsValid() bool {
+	switch e {
+	case PreferenceTypeString, PreferenceTypeBoolean, PreferenceTypeInt, PreferenceTypeFloat, PreferenceTypeJSON:
+		return true
+	}
+	return false
+}
+
+func (e PreferenceType) String() string {
+	return string(e)
+}
+
+func (e *PreferenceType) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = PreferenceType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid PreferenceType", str)
+	}
+	return nil
+}
+
+func (e PreferenceType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *PreferenceType) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e PreferenceType) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,5 @@
+package resolver
+
+// maxAuthEvents caps how many audit trail entries a single authEvents query
+// returns, newest first; narrow the time range to see older ones.
+const maxAuthEvents = 1000
@@ -0,0 +1,69 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+
+	"user-service/internal/auth"
+	dbmodel "user-service/internal/model"
+)
+
+// requireRole returns the caller's user ID if the caller has at least minRole.
+func requireRole(ctx context.Context, minRole string) (string, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return "", errors.New("unauthenticated")
+	}
+
+	role, err := auth.GetRoleFromContext(ctx)
+	if err != nil || !dbmodel.HasRole(role, minRole) {
+		return "", errors.New("only " + minRole + "s can manage users")
+	}
+
+	return userID, nil
+}
+
+// requireManager returns the caller's user ID if the caller is a manager or
+// an admin.
+func requireManager(ctx context.Context) (string, error) {
+	return requireRole(ctx, dbmodel.RoleManager)
+}
+
+// checkCanManage refuses acting on a user whose role is above the caller's,
+// so managers can't modify admins.
+func checkCanManage(ctx context.Context, target *dbmodel.User) error {
+	role, _ := auth.GetRoleFromContext(ctx)
+	if !dbmodel.HasRole(role, target.Role) {
+		return errors.New("only admins can manage admin accounts")
+	}
+	return nil
+}
+
+// checkCanAssignRole refuses unknown roles and roles above the caller's.
+func checkCanAssignRole(ctx context.Context, role string) error {
+	if !dbmodel.IsValidRole(role) {
+		return errors.New("invalid role: must be 'admin', 'manager' or 'member'")
+	}
+	callerRole, _ := auth.GetRoleFromContext(ctx)
+	if !dbmodel.HasRole(callerRole, role) {
+		return errors.New("cannot assign a role above your own")
+	}
+	return nil
+}
+
+// requireScope lets through authenticated users, and service accounts that
+// were granted scope.
+func requireScope(ctx context.Context, scope string) error {
+	if _, err := auth.GetUserIDFromContext(ctx); err == nil {
+		return nil
+	}
+
+	principal, err := auth.GetServicePrincipalFromContext(ctx)
+	if err != nil {
+		return errors.New("unauthenticated")
+	}
+	if !principal.HasScope(scope) {
+		return errors.New("service account lacks scope " + scope)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package resolver
+
+import (
+	"user-service/graph/generated"
+	gqlmodel "user-service/graph/model"
+)
+
+// unboundedListSize is what list fields without a page size are assumed to
+// return when estimating query cost.
+const unboundedListSize = 100
+
+// Complexity weighs list fields by how many items they can return, so the
+// complexity limit and the query budget see the real cost of a query.
+func Complexity() generated.ComplexityRoot {
+	var c generated.ComplexityRoot
+
+	c.Query.FetchUsers = func(childComplexity int) int {
+		return unboundedListSize * childComplexity
+	}
+	c.Query.UsersByIds = func(childComplexity int, ids []string) int {
+		return max(len(ids), 1) * childComplexity
+	}
+	c.Query.Users = func(childComplexity int, filter *gqlmodel.UserFilter, first *int, after *string) int {
+		size := defaultPageSize
+		if first != nil && *first > 0 && *first <= maxPageSize {
+			size = *first
+		}
+		return size * childComplexity
+	}
+
+	return c
+}
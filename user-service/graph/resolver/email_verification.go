@@ -0,0 +1,64 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"user-service/internal/auth"
+	"user-service/internal/mail"
+	dbmodel "user-service/internal/model"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+const (
+	// emailVerificationTTL is how long an emailed verification link stays valid.
+	emailVerificationTTL = 24 * time.Hour
+	errEmailNotVerified  = "EMAIL_NOT_VERIFIED"
+)
+
+// sendEmailVerification emails a link verifying the current address of user.
+// Links sent earlier stop working, in particular those for a previous address.
+func (r *Resolver) sendEmailVerification(ctx context.Context, user *dbmodel.User) error {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&dbmodel.EmailVerificationToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&dbmodel.EmailVerificationToken{
+			UserID:    user.UserID,
+			Email:     user.Email,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(emailVerificationTTL),
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to verify your email address. It expires in %d hours.\n\n%s/verify-email?token=%s\n\nIf you didn't expect this, you can ignore this email.\n",
+			user.Username, int(emailVerificationTTL.Hours()), r.FrontendURL, token),
+	})
+}
+
+// emailNotVerifiedError refuses a login of a user whose address isn't
+// verified yet, with a code clients can use to offer requestEmailVerification.
+func emailNotVerifiedError() error {
+	return &gqlerror.Error{
+		Message: "email address is not verified",
+		Extensions: map[string]interface{}{
+			"code": errEmailNotVerified,
+		},
+	}
+}
@@ -0,0 +1,36 @@
+package resolver
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.78
+
+import (
+	"context"
+	"fmt"
+	"user-service/graph/generated"
+	"user-service/internal/auth"
+	"user-service/internal/loader"
+	dbmodel "user-service/internal/model"
+)
+
+func (r *entityResolver) FindUserByUserID(ctx context.Context, userID string) (*dbmodel.User, error) {
+	if err := requireScope(ctx, auth.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	// Representations of one gateway request are resolved in a single batch
+	user, err := loader.For(ctx).Load(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("user %s not found", userID)
+	}
+
+	return user, nil
+}
+
+// Entity returns generated.EntityResolver implementation.
+func (r *Resolver) Entity() generated.EntityResolver { return &entityResolver{r} }
+
+type entityResolver struct{ *Resolver }
@@ -0,0 +1,26 @@
+package resolver
+
+import (
+	"slices"
+
+	gqlmodel "user-service/graph/model"
+	"user-service/internal/pubsub"
+)
+
+// matchesUserChangeFilter reports whether change passes every criterion set
+// in filter.
+func matchesUserChangeFilter(change *pubsub.UserChange, filter *gqlmodel.UserChangeFilter) bool {
+	if filter == nil {
+		return true
+	}
+	if len(filter.Types) > 0 && !slices.Contains(filter.Types, change.Type) {
+		return false
+	}
+	if filter.UserID != nil && *filter.UserID != change.User.UserID {
+		return false
+	}
+	if filter.Role != nil && *filter.Role != change.User.Role {
+		return false
+	}
+	return true
+}
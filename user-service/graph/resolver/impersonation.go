@@ -0,0 +1,30 @@
+package resolver
+
+import (
+	"context"
+
+	"user-service/internal/auth"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	// maxImpersonationReason bounds the reason recorded in the audit trail.
+	maxImpersonationReason = 500
+	errImpersonating       = "IMPERSONATION_FORBIDDEN"
+)
+
+// forbidImpersonation refuses sensitive mutations to admins acting as another
+// user: changing credentials (password, 2FA, email, API keys) and ending the
+// user's sessions.
+func forbidImpersonation(ctx context.Context) error {
+	if _, ok := auth.GetActorFromContext(ctx); !ok {
+		return nil
+	}
+	return &gqlerror.Error{
+		Message: "not allowed while impersonating a user",
+		Extensions: map[string]interface{}{
+			"code": errImpersonating,
+		},
+	}
+}
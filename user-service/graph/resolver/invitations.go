@@ -0,0 +1,37 @@
+package resolver
+
+import (
+	"context"
+	"fmt"
+	netmail "net/mail"
+	"time"
+
+	"user-service/internal/auth"
+	"user-service/internal/mail"
+	dbmodel "user-service/internal/model"
+)
+
+// invitationTTL is how long an emailed invitation stays valid.
+const invitationTTL = 7 * 24 * time.Hour
+
+// isEmailAddress accepts a bare address such as ann@example.com, without a
+// display name or anything that could end up in mail headers.
+func isEmailAddress(email string) bool {
+	addr, err := netmail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
+// sendInvitation emails the link to accept invitation.
+func (r *Resolver) sendInvitation(ctx context.Context, invitation *dbmodel.Invitation) error {
+	token, err := auth.GenerateInvitationToken(invitation.UserID, invitation.ID, invitation.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return r.Mailer.Send(ctx, mail.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited",
+		Body: fmt.Sprintf("Hi,\n\nYou have been invited to join as %s. Use the link below to choose your username and password. It expires in %d days.\n\n%s/accept-invitation?token=%s\n\nIf you weren't expecting this, you can ignore this email.\n",
+			invitation.Role, int(invitationTTL.Hours()/24), r.FrontendURL, token),
+	})
+}
@@ -0,0 +1,53 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"time"
+
+	"user-service/internal/audit"
+	"user-service/internal/auth"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// loginFailed records a failed login for email, in the audit trail and for
+// the login protection, and answers it with failure once the progressive
+// delay has passed.
+func (r *Resolver) loginFailed(ctx context.Context, email string, failure error) error {
+	r.Audit.Record(ctx, audit.LoginFailed, "", email, failure.Error())
+
+	delay, err := r.LoginLimiter.RecordFailure(ctx, email, auth.GetClientIPFromContext(ctx))
+	var blocked *auth.LoginBlockedError
+	if errors.As(err, &blocked) {
+		return loginBlockedError(blocked)
+	}
+	if err != nil {
+		slog.Error("Failed to record failed login", "error", err)
+		return failure
+	}
+
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+	return failure
+}
+
+// loginBlockedError surfaces a refused login with its code and the number of
+// seconds after which the client may retry.
+func loginBlockedError(err *auth.LoginBlockedError) error {
+	return &gqlerror.Error{
+		Message: err.Error(),
+		Extensions: map[string]interface{}{
+			"code":       err.Code,
+			"retryAfter": int(math.Ceil(err.RetryAfter.Seconds())),
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"user-service/internal/auth"
+	"user-service/internal/mail"
+	dbmodel "user-service/internal/model"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
+)
+
+var errInvalidMagicLink = errors.New("invalid or expired login link")
+
+// magicLinkEnabled reports whether admins enabled login links for role.
+func (r *Resolver) magicLinkEnabled(role string) (bool, error) {
+	var setting dbmodel.RoleSetting
+	err := r.DB.Where("role = ?", role).First(&setting).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return setting.MagicLinkEnabled, nil
+}
+
+// sendMagicLink emails user a link that logs them in once.
+func (r *Resolver) sendMagicLink(ctx context.Context, user *dbmodel.User) error {
+	token, hash, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := r.DB.Create(&dbmodel.MagicLinkToken{
+		UserID:    user.UserID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(r.MagicLinkTTL),
+	}).Error; err != nil {
+		return err
+	}
+
+	return r.Mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to log in. It works once and expires in %d minutes.\n\n%s/magic-link?token=%s\n\nIf you didn't ask for this, you can ignore this email.\n",
+			user.Username, int(r.MagicLinkTTL.Minutes()), r.FrontendURL, token),
+	})
+}
+
+// tooManyMagicLinksError refuses a link request over the per-email limit,
+// like loginBlockedError.
+func tooManyMagicLinksError(retryAfter time.Duration) error {
+	return &gqlerror.Error{
+		Message: "too many login links requested, try again later",
+		Extensions: map[string]interface{}{
+			"code":       auth.LoginErrorTooManyAttempts,
+			"retryAfter": int(math.Ceil(retryAfter.Seconds())),
+		},
+	}
+}
@@ -0,0 +1,161 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	gqlmodel "user-service/graph/model"
+	"user-service/internal/auth"
+	dbmodel "user-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+var errInvalidMFACode = errors.New("invalid verification code")
+
+// requiresMFA reports whether the role of user may only log in with 2FA.
+func (r *Resolver) requiresMFA(user *dbmodel.User) bool {
+	return slices.Contains(r.MFARequiredRoles, user.Role)
+}
+
+// mfaChallenge answers a login whose password was right but that still needs
+// a second factor, or a TOTP enrollment first when enroll is set.
+func mfaChallenge(user *dbmodel.User, enroll bool) (*gqlmodel.AuthPayload, error) {
+	token, err := auth.GenerateMFAToken(user.UserID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+	return &gqlmodel.AuthPayload{
+		User:                  user,
+		MfaRequired:           true,
+		MfaEnrollmentRequired: enroll,
+		MfaToken:              &token,
+	}, nil
+}
+
+// parseMFAToken returns the claims of a challenge token that hasn't been
+// exchanged yet.
+func (r *Resolver) parseMFAToken(ctx context.Context, mfaToken string) (*auth.Claims, error) {
+	claims, err := auth.ParseMFAToken(mfaToken)
+	if err != nil {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+	revoked, err := r.Revocations.IsRevoked(ctx, claims.ID)
+	if err != nil || revoked {
+		return nil, errors.New("invalid or expired mfa token")
+	}
+	return claims, nil
+}
+
+// consumeMFAToken makes sure a challenge token finishes only one login.
+func (r *Resolver) consumeMFAToken(ctx context.Context, claims *auth.Claims) error {
+	return r.Revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+// totpUser loads the user a TOTP mutation acts on: the holder of mfaToken
+// while a login is finishing a required enrollment, otherwise the caller. The
+// claims are nil for the caller. Admins impersonating a user can't change
+// their 2FA.
+func (r *Resolver) totpUser(ctx context.Context, mfaToken *string) (*dbmodel.User, *auth.Claims, error) {
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	var userID string
+	var claims *auth.Claims
+	if mfaToken != nil {
+		var err error
+		if claims, err = r.parseMFAToken(ctx, *mfaToken); err != nil {
+			return nil, nil, err
+		}
+		userID = claims.UserID
+	} else {
+		var err error
+		if userID, err = auth.GetUserIDFromContext(ctx); err != nil {
+			return nil, nil, errors.New("unauthorized")
+		}
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, nil, errors.New("user not found")
+	}
+	// A challenge token only stands in for a session when the login can't
+	// finish without enrolling; everyone else enrolls once logged in
+	if claims != nil && !r.requiresMFA(&user) {
+		return nil, nil, errors.New("invalid or expired mfa token")
+	}
+	return &user, claims, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code of user or one of
+// their unused recovery codes. Both can only be used once.
+func (r *Resolver) verifySecondFactor(user *dbmodel.User, code string) error {
+	if user.TOTPSecret == "" {
+		return errInvalidMFACode
+	}
+
+	if !strings.Contains(code, "-") && len(strings.TrimSpace(code)) == 6 {
+		return r.useTOTPCode(r.DB, user, code)
+	}
+
+	res := r.DB.Model(&dbmodel.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL",
+			user.UserID, auth.HashOpaqueToken(auth.NormalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errInvalidMFACode
+	}
+	return nil
+}
+
+// useTOTPCode checks code against the secret of user and records its time
+// step, refusing codes from the same or an earlier step than the last one.
+func (r *Resolver) useTOTPCode(db *gorm.DB, user *dbmodel.User, code string) error {
+	step, ok, err := r.TOTP.Validate(user.TOTPSecret, code, time.Now())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidMFACode
+	}
+
+	res := db.Model(&dbmodel.User{}).
+		Where("user_id = ? AND totp_last_step < ?", user.UserID, step).
+		Update("totp_last_step", step)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errInvalidMFACode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+// replaceRecoveryCodes issues a fresh set of recovery codes for userID,
+// invalidating the previous ones.
+func replaceRecoveryCodes(tx *gorm.DB, userID string) ([]string, error) {
+	codes, err := auth.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&dbmodel.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	rows := make([]dbmodel.RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = dbmodel.RecoveryCode{UserID: userID, CodeHash: auth.HashOpaqueToken(code)}
+	}
+	if err := tx.Create(&rows).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
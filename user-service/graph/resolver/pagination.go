@@ -0,0 +1,41 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	dbmodel "user-service/internal/model"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// encodeUserCursor returns an opaque cursor for keyset pagination over
+// (created_at, user_id).
+func encodeUserCursor(user *dbmodel.User) string {
+	raw := user.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + user.UserID
+	return base64.URLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeUserCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", errors.New("invalid cursor")
+	}
+
+	createdAt, userID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, "", errors.New("invalid cursor")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, "", errors.New("invalid cursor")
+	}
+
+	return t, userID, nil
+}
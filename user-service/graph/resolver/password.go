@@ -0,0 +1,36 @@
+package resolver
+
+import (
+	"log/slog"
+	"time"
+
+	dbmodel "user-service/internal/model"
+)
+
+// passwordResetTTL is how long an emailed password reset link stays valid.
+const passwordResetTTL = 30 * time.Minute
+
+// upgradePasswordHash rehashes the password user just logged in with when its
+// stored hash predates the current algorithm or parameters. The password is
+// unchanged, so the history is left alone. Failures only delay the upgrade to
+// a later login.
+func (r *Resolver) upgradePasswordHash(user *dbmodel.User, password string) {
+	if !r.Passwords.NeedsRehash(user.PasswordHash) {
+		return
+	}
+
+	hash, err := r.Passwords.Hash(password)
+	if err != nil {
+		slog.Error("Failed to rehash password", "userId", user.UserID, "error", err)
+		return
+	}
+	// Only replace the hash that was verified, in case the password changed meanwhile
+	err = r.DB.Model(&dbmodel.User{}).
+		Where("user_id = ? AND password_hash = ?", user.UserID, user.PasswordHash).
+		Update("password_hash", hash).Error
+	if err != nil {
+		slog.Error("Failed to rehash password", "userId", user.UserID, "error", err)
+		return
+	}
+	user.PasswordHash = hash
+}
@@ -0,0 +1,125 @@
+package resolver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	gqlmodel "user-service/graph/model"
+	dbmodel "user-service/internal/model"
+	"user-service/internal/profile"
+)
+
+const (
+	// maxPreferenceSize is the largest JSON encoded preference value.
+	maxPreferenceSize = 4096
+	// maxPreferencesPerUser bounds how much a client can store.
+	maxPreferencesPerUser = 100
+)
+
+var preferenceKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.:-]{1,64}$`)
+
+// profileUpdates validates the fields set in input and returns the columns to
+// update. Empty strings clear a field.
+func profileUpdates(input gqlmodel.UpdateProfileInput) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	set := func(column string, value *string, normalize func(string) (string, error)) error {
+		if value == nil {
+			return nil
+		}
+		v := strings.TrimSpace(*value)
+		if v == "" {
+			updates[column] = nil
+			return nil
+		}
+		v, err := normalize(v)
+		if err != nil {
+			return err
+		}
+		updates[column] = v
+		return nil
+	}
+
+	if err := set("display_name", input.DisplayName, profile.Text("display name")); err != nil {
+		return nil, err
+	}
+	if err := set("avatar_url", input.AvatarURL, profile.NormalizeAvatarURL); err != nil {
+		return nil, err
+	}
+	if err := set("timezone", input.Timezone, profile.NormalizeTimezone); err != nil {
+		return nil, err
+	}
+	if err := set("locale", input.Locale, profile.NormalizeLocale); err != nil {
+		return nil, err
+	}
+	if err := set("job_title", input.JobTitle, profile.Text("job title")); err != nil {
+		return nil, err
+	}
+	return updates, nil
+}
+
+// encodePreference checks that value has the type prefType and encodes it for
+// storage.
+func encodePreference(prefType gqlmodel.PreferenceType, value any) (string, error) {
+	var ok bool
+	switch prefType {
+	case gqlmodel.PreferenceTypeString:
+		_, ok = value.(string)
+	case gqlmodel.PreferenceTypeBoolean:
+		_, ok = value.(bool)
+	case gqlmodel.PreferenceTypeInt:
+		switch v := value.(type) {
+		case int, int64:
+			ok = true
+		case json.Number:
+			_, err := v.Int64()
+			ok = err == nil
+		}
+	case gqlmodel.PreferenceTypeFloat:
+		switch v := value.(type) {
+		case int, int64:
+			ok = true
+		case float64:
+			ok = !math.IsInf(v, 0) && !math.IsNaN(v)
+		case json.Number:
+			_, err := v.Float64()
+			ok = err == nil
+		}
+	case gqlmodel.PreferenceTypeJSON:
+		ok = true
+	}
+	if !ok {
+		return "", fmt.Errorf("value is not of type %s", prefType)
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", errors.New("value can't be encoded")
+	}
+	if len(encoded) > maxPreferenceSize {
+		return "", fmt.Errorf("value must be at most %d bytes", maxPreferenceSize)
+	}
+	return string(encoded), nil
+}
+
+// toPreference maps a stored preference to its GraphQL type.
+func toPreference(pref *dbmodel.UserPreference) (*gqlmodel.Preference, error) {
+	// Keep numbers as stored rather than rounding them through float64
+	decoder := json.NewDecoder(bytes.NewReader([]byte(pref.Value)))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid stored preference %q: %w", pref.Key, err)
+	}
+
+	return &gqlmodel.Preference{
+		Key:       pref.Key,
+		Type:      gqlmodel.PreferenceType(pref.Type),
+		Value:     value,
+		UpdatedAt: pref.UpdatedAt,
+	}, nil
+}
@@ -1,11 +1,38 @@
 package resolver
 
-import "gorm.io/gorm"
+import (
+	"time"
+
+	"user-service/internal/audit"
+	"user-service/internal/auth"
+	"user-service/internal/events"
+	"user-service/internal/mail"
+	"user-service/internal/password"
+	"user-service/internal/pubsub"
+
+	"gorm.io/gorm"
+)
 
 // This file will not be regenerated automatically.
 //
 // It serves as dependency injection for your app, add any dependencies you require here.
 
-type Resolver struct{
-	DB *gorm.DB
+type Resolver struct {
+	DB           *gorm.DB
+	Revocations  *auth.RevocationStore
+	Events       *events.UserEvents
+	Mailer       mail.Mailer
+	FrontendURL  string
+	Passwords    *password.Policy
+	LoginLimiter *auth.LoginLimiter
+	// RequireVerifiedEmail refuses logins of users with an unverified address.
+	RequireVerifiedEmail bool
+	TOTP                 *auth.TOTP
+	// MFARequiredRoles may only log in with two-factor authentication.
+	MFARequiredRoles []string
+	// MagicLinkTTL is how long an emailed login link stays valid.
+	MagicLinkTTL     time.Duration
+	MagicLinkLimiter *auth.MagicLinkLimiter
+	UserChanges      *pubsub.UserChanges
+	Audit            *audit.Trail
 }
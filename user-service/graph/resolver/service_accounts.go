@@ -0,0 +1,102 @@
+package resolver
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	gqlmodel "user-service/graph/model"
+	"user-service/internal/auth"
+	dbmodel "user-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyRotationGrace is how long the previous keys of a service account keep
+// working after rotateApiKey, so deployments can switch over.
+const apiKeyRotationGrace = 24 * time.Hour
+
+// normalizeScopes deduplicates scopes and refuses unknown ones.
+func normalizeScopes(scopes []string) ([]string, error) {
+	var result []string
+	for _, scope := range scopes {
+		if !slices.Contains(auth.KnownScopes, scope) {
+			return nil, errors.New("unknown scope: " + scope)
+		}
+		if !slices.Contains(result, scope) {
+			result = append(result, scope)
+		}
+	}
+	if len(result) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+	return result, nil
+}
+
+// issueAPIKey creates a key for account inside tx and returns it in clear.
+func issueAPIKey(tx *gorm.DB, account *dbmodel.ServiceAccount) (string, error) {
+	key, prefix, hash, err := auth.NewAPIKey()
+	if err != nil {
+		return "", err
+	}
+	return key, tx.Create(&dbmodel.APIKey{
+		ServiceAccountID: account.ID,
+		Prefix:           prefix,
+		KeyHash:          hash,
+	}).Error
+}
+
+// loadServiceAccounts maps accounts to their GraphQL type, keys included.
+func (r *Resolver) loadServiceAccounts(accounts []dbmodel.ServiceAccount) ([]*gqlmodel.ServiceAccount, error) {
+	ids := make([]string, len(accounts))
+	for i, account := range accounts {
+		ids[i] = account.ID
+	}
+
+	var keys []dbmodel.APIKey
+	if len(ids) > 0 {
+		if err := r.DB.Where("service_account_id IN ?", ids).Order("created_at").Find(&keys).Error; err != nil {
+			return nil, err
+		}
+	}
+	keysByAccount := make(map[string][]*gqlmodel.APIKey)
+	for _, key := range keys {
+		keysByAccount[key.ServiceAccountID] = append(keysByAccount[key.ServiceAccountID], &gqlmodel.APIKey{
+			ID:         key.ID,
+			Prefix:     key.Prefix,
+			ExpiresAt:  key.ExpiresAt,
+			RevokedAt:  key.RevokedAt,
+			LastUsedAt: key.LastUsedAt,
+			CreatedAt:  key.CreatedAt,
+		})
+	}
+
+	result := make([]*gqlmodel.ServiceAccount, len(accounts))
+	for i, account := range accounts {
+		result[i] = &gqlmodel.ServiceAccount{
+			ID:        account.ID,
+			Name:      account.Name,
+			Scopes:    strings.Fields(account.Scopes),
+			Disabled:  account.DisabledAt != nil,
+			Keys:      keysByAccount[account.ID],
+			CreatedAt: account.CreatedAt,
+		}
+		if result[i].Keys == nil {
+			result[i].Keys = []*gqlmodel.APIKey{}
+		}
+	}
+	return result, nil
+}
+
+// serviceAccountCredentials answers the mutations that issue a key.
+func (r *Resolver) serviceAccountCredentials(account *dbmodel.ServiceAccount, key string) (*gqlmodel.ServiceAccountCredentials, error) {
+	loaded, err := r.loadServiceAccounts([]dbmodel.ServiceAccount{*account})
+	if err != nil {
+		return nil, err
+	}
+	return &gqlmodel.ServiceAccountCredentials{
+		ServiceAccount: loaded[0],
+		APIKey:         key,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package resolver
+
+import (
+	gqlmodel "user-service/graph/model"
+	dbmodel "user-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// activeSessions scopes a query to the sessions that can still be used, that
+// is not revoked and with an unexpired refresh token left.
+func activeSessions(db *gorm.DB) *gorm.DB {
+	return db.Where("sessions.revoked_at IS NULL").
+		Where("EXISTS (SELECT 1 FROM refresh_tokens WHERE refresh_tokens.family_id = sessions.id AND refresh_tokens.revoked_at IS NULL AND refresh_tokens.expires_at > NOW())")
+}
+
+// toSession maps a session to its GraphQL type; currentID is the session of
+// the caller's token.
+func toSession(session *dbmodel.Session, currentID string) *gqlmodel.Session {
+	return &gqlmodel.Session{
+		ID:         session.ID,
+		UserAgent:  session.UserAgent,
+		IPAddress:  session.IPAddress,
+		Current:    session.ID == currentID,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+	}
+}
@@ -0,0 +1,101 @@
+package resolver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	gqlmodel "user-service/graph/model"
+	"user-service/internal/auth"
+	dbmodel "user-service/internal/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// newRefreshToken prepares the next refresh token of familyID for userID.
+// An empty familyID starts a new family, as happens on login.
+func newRefreshToken(userID, familyID string) *dbmodel.RefreshToken {
+	if familyID == "" {
+		familyID = uuid.NewString()
+	}
+	return &dbmodel.RefreshToken{
+		TokenID:   uuid.NewString(),
+		FamilyID:  familyID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(auth.RefreshTokenTTL),
+	}
+}
+
+// signTokenPair builds the AuthPayload for user from an already persisted
+// refresh token row.
+func signTokenPair(user *dbmodel.User, refresh *dbmodel.RefreshToken) (*gqlmodel.AuthPayload, error) {
+	accessToken, err := auth.GenerateAccessToken(user.UserID, user.Role, refresh.FamilyID)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	refreshToken, err := auth.GenerateRefreshToken(user.UserID, refresh.TokenID, refresh.ExpiresAt)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	return &gqlmodel.AuthPayload{
+		Token:        &accessToken,
+		RefreshToken: &refreshToken,
+		User:         user,
+	}, nil
+}
+
+// startSession records a session for the device in ctx, opens its refresh
+// token family and signs the first token pair. method says how the user
+// logged in, for the audit trail.
+func (r *Resolver) startSession(ctx context.Context, user *dbmodel.User, method string) (*gqlmodel.AuthPayload, error) {
+	refresh := newRefreshToken(user.UserID, "")
+	session := &dbmodel.Session{
+		ID:         refresh.FamilyID,
+		UserID:     user.UserID,
+		UserAgent:  auth.GetUserAgentFromContext(ctx),
+		IPAddress:  auth.GetClientIPFromContext(ctx),
+		LastSeenAt: time.Now(),
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+		return tx.Create(refresh).Error
+	})
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	r.Audit.RecordLogin(ctx, user, method)
+
+	return signTokenPair(user, refresh)
+}
+
+// revokeTokenFamily revokes every still-active refresh token of a family and
+// ends its session.
+func revokeTokenFamily(db *gorm.DB, familyID string) error {
+	now := time.Now()
+	if err := db.Model(&dbmodel.Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return db.Model(&dbmodel.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+// endSession revokes the token family sessionID, and its access tokens right
+// away in every service.
+func (r *Resolver) endSession(ctx context.Context, sessionID string) error {
+	if err := revokeTokenFamily(r.DB, sessionID); err != nil {
+		return err
+	}
+	return r.Revocations.RevokeSession(ctx, sessionID)
+}
@@ -8,28 +8,56 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 	"user-service/graph/generated"
 	gqlmodel "user-service/graph/model"
+	"user-service/internal/account"
+	"user-service/internal/audit"
 	"user-service/internal/auth"
+	"user-service/internal/database"
+	"user-service/internal/events"
+	"user-service/internal/loader"
+	"user-service/internal/mail"
 	dbmodel "user-service/internal/model"
+	"user-service/internal/pubsub"
 
-	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+func (r *userResolver) Preferences(ctx context.Context, obj *dbmodel.User) ([]*gqlmodel.Preference, error) {
+	// Empty rather than an error for other users, so lists of users that
+	// select preferences still resolve
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil || userID != obj.UserID {
+		return []*gqlmodel.Preference{}, nil
+	}
+
+	var prefs []dbmodel.UserPreference
+	if err := r.DB.Where("user_id = ?", obj.UserID).Order("key").Find(&prefs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch preferences: %w", err)
+	}
+
+	result := make([]*gqlmodel.Preference, len(prefs))
+	for i := range prefs {
+		if result[i], err = toPreference(&prefs[i]); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
 func (r *mutationResolver) CreateUser(ctx context.Context, input gqlmodel.CreateUserInput) (*dbmodel.User, error) {
 	// Lấy thông tin từ context (đã được gắn ở middleware auth)
-	role, err := auth.GetRoleFromContext(ctx)
-	fmt.Println("role", role)
-	fmt.Println("err", err)
-	if err != nil || role != "manager" {
-		return nil, errors.New("unauthorized")
-}
-	if role != "manager" {
-		return nil, errors.New("only managers can create users")
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if input.Role != "manager" && input.Role != "member" {
-		return nil, errors.New("invalid role: must be 'manager' or 'member'")
+	if err := checkCanAssignRole(ctx, input.Role); err != nil {
+		return nil, err
 	}
 
 	// Check duplicate email
@@ -38,7 +66,11 @@ func (r *mutationResolver) CreateUser(ctx context.Context, input gqlmodel.Create
 		return nil, errors.New("email already in use")
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	if err := r.Passwords.Validate(input.Password); err != nil {
+		return nil, err
+	}
+
+	hash, err := r.Passwords.Hash(input.Password)
 	if err != nil {
 		return nil, errors.New("failed to hash password")
 	}
@@ -47,64 +79,1609 @@ func (r *mutationResolver) CreateUser(ctx context.Context, input gqlmodel.Create
 		Username:     input.Username,
 		Email:        input.Email,
 		Role:         input.Role,
-		PasswordHash: string(hash),
+		Status:       dbmodel.UserStatusActive,
+		PasswordHash: hash,
 	}
 
 	if err := r.DB.Create(user).Error; err != nil {
 		return nil, err
 	}
 
+	if err := r.sendEmailVerification(ctx, user); err != nil {
+		slog.Error("Failed to send email verification", "userId", user.UserID, "error", err)
+	}
+
+	r.Events.Publish(ctx, events.UserCreated, user, callerID)
+
 	return user, nil
 }
 
+func (r *mutationResolver) UpdateUser(ctx context.Context, id string, input gqlmodel.UpdateUserInput) (*dbmodel.User, error) {
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", id).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return nil, err
+	}
+
+	if input.Username != nil {
+		if strings.TrimSpace(*input.Username) == "" {
+			return nil, errors.New("username cannot be empty")
+		}
+		user.Username = *input.Username
+	}
+
+	emailChanged := false
+	if input.Email != nil && *input.Email != user.Email {
+		// The address receives password reset links
+		if err := forbidImpersonation(ctx); err != nil {
+			return nil, err
+		}
+		if !isEmailAddress(*input.Email) {
+			return nil, errors.New("invalid email address")
+		}
+		var existing dbmodel.User
+		if err := r.DB.Where("email = ? AND user_id <> ?", *input.Email, user.UserID).First(&existing).Error; err == nil {
+			return nil, errors.New("email already in use")
+		}
+		user.Email = *input.Email
+		user.EmailVerified = false
+		emailChanged = true
+	}
+
+	if err := r.DB.Save(&user).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// Pending users verify their address by accepting the invitation
+	if emailChanged && user.Status != dbmodel.UserStatusPending {
+		if err := r.sendEmailVerification(ctx, &user); err != nil {
+			slog.Error("Failed to send email verification", "userId", user.UserID, "error", err)
+		}
+	}
+
+	r.Events.Publish(ctx, events.UserUpdated, &user, callerID)
+
+	return &user, nil
+}
+
+func (r *mutationResolver) ChangeRole(ctx context.Context, id string, role string) (*dbmodel.User, error) {
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if callerID == id {
+		return nil, errors.New("cannot change your own role")
+	}
+
+	if err := checkCanAssignRole(ctx, role); err != nil {
+		return nil, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", id).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return nil, err
+	}
+
+	if err := r.DB.Model(&user).Update("role", role).Error; err != nil {
+		return nil, fmt.Errorf("failed to change role: %w", err)
+	}
+
+	// Access tokens carry the role, so force a refresh to pick up the new one
+	if err := r.Revocations.RevokeUser(ctx, user.UserID); err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	r.Events.Publish(ctx, events.UserRoleChanged, &user, callerID)
+
+	return &user, nil
+}
+
+func (r *mutationResolver) DeactivateUser(ctx context.Context, id string) (*dbmodel.User, error) {
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if callerID == id {
+		return nil, errors.New("cannot deactivate your own account")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", id).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return nil, err
+	}
+	if user.Status == dbmodel.UserStatusPending {
+		return nil, errors.New("user has not accepted their invitation yet, revoke the invitation instead")
+	}
+
+	if err := r.DB.Model(&user).Update("status", dbmodel.UserStatusDeactivated).Error; err != nil {
+		return nil, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	if err := account.RevokeTokens(r.DB, user.UserID); err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	if err := r.Revocations.RevokeUser(ctx, user.UserID); err != nil {
+		return nil, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	r.Events.Publish(ctx, events.UserDeactivated, &user, callerID)
+
+	return &user, nil
+}
+
+func (r *mutationResolver) ReactivateUser(ctx context.Context, id string) (*dbmodel.User, error) {
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", id).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return nil, err
+	}
+	if user.Status == dbmodel.UserStatusPending {
+		return nil, errors.New("user has not accepted their invitation yet")
+	}
+
+	if err := r.DB.Model(&user).Update("status", dbmodel.UserStatusActive).Error; err != nil {
+		return nil, fmt.Errorf("failed to reactivate user: %w", err)
+	}
+
+	r.Events.Publish(ctx, events.UserReactivated, &user, callerID)
+
+	return &user, nil
+}
+
+func (r *mutationResolver) UnlockUser(ctx context.Context, id string) (bool, error) {
+	if _, err := requireManager(ctx); err != nil {
+		return false, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", id).First(&user).Error; err != nil {
+		return false, errors.New("user not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return false, err
+	}
+
+	if err := r.LoginLimiter.Unlock(ctx, user.Email); err != nil {
+		return false, fmt.Errorf("failed to unlock user: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) DeleteUser(ctx context.Context, id string) (bool, error) {
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return false, err
+	}
+	if callerID == id {
+		return false, errors.New("cannot delete your own account")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", id).First(&user).Error; err != nil {
+		return false, errors.New("user not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return false, err
+	}
+
+	if err := account.Delete(r.DB, &user); err != nil {
+		return false, fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	if err := r.Revocations.RevokeUser(ctx, user.UserID); err != nil {
+		return false, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	r.Events.Publish(ctx, events.UserDeleted, &user, callerID)
+
+	return true, nil
+}
 
 func (r *mutationResolver) Login(ctx context.Context, input gqlmodel.LoginInput) (*gqlmodel.AuthPayload, error) {
+	err := r.LoginLimiter.Check(ctx, input.Email, auth.GetClientIPFromContext(ctx))
+	var blocked *auth.LoginBlockedError
+	if errors.As(err, &blocked) {
+		r.Audit.Record(ctx, audit.LoginFailed, "", input.Email, blocked.Code)
+		return nil, loginBlockedError(blocked)
+	}
+	if err != nil {
+		return nil, errors.New("login is temporarily unavailable")
+	}
+
 	var user dbmodel.User
 	if err := r.DB.Where("email = ?", input.Email).First(&user).Error; err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, r.loginFailed(ctx, input.Email, errInvalidCredentials)
+	}
+
+	if !r.Passwords.Verify(input.Password, user.PasswordHash) {
+		return nil, r.loginFailed(ctx, input.Email, errInvalidCredentials)
+	}
+	r.upgradePasswordHash(&user, input.Password)
+
+	if user.Status == dbmodel.UserStatusDeactivated {
+		r.Audit.Record(ctx, audit.LoginFailed, user.UserID, user.Email, "account is deactivated")
+		return nil, errors.New("account is deactivated")
+	}
+	if r.RequireVerifiedEmail && !user.EmailVerified {
+		r.Audit.Record(ctx, audit.LoginFailed, user.UserID, user.Email, "email not verified")
+		return nil, emailNotVerifiedError()
+	}
+
+	// Failed logins are only forgotten once the second factor passed too, so
+	// a known password doesn't reset the count of wrong codes
+	if user.TOTPEnabled {
+		return mfaChallenge(&user, false)
+	}
+	if r.requiresMFA(&user) {
+		return mfaChallenge(&user, true)
+	}
+
+	if err := r.LoginLimiter.Reset(ctx, input.Email); err != nil {
+		slog.Error("Failed to reset failed logins", "userId", user.UserID, "error", err)
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(input.Password))
+	return r.startSession(ctx, &user, "password")
+}
+
+func (r *mutationResolver) RefreshToken(ctx context.Context, token string) (*gqlmodel.AuthPayload, error) {
+	claims, err := auth.ParseRefreshToken(token)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, errors.New("invalid refresh token")
+	}
+
+	var stored dbmodel.RefreshToken
+	if err := r.DB.Where("token_id = ?", claims.ID).First(&stored).Error; err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// A revoked token being presented again means it was stolen or replayed:
+	// invalidate the whole family so neither party can keep refreshing.
+	if stored.RevokedAt != nil {
+		if err := r.endSession(ctx, stored.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		return nil, errRefreshTokenReused
+	}
+
+	if time.Now().After(stored.ExpiresAt) {
+		return nil, errors.New("refresh token expired")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", stored.UserID).First(&user).Error; err != nil {
+		return nil, errors.New("invalid refresh token")
+	}
+	if user.Status == dbmodel.UserStatusDeactivated {
+		return nil, errors.New("account is deactivated")
 	}
 
-	token, err := auth.GenerateAccessToken(user.UserID, user.Role)
+	var payload *gqlmodel.AuthPayload
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		next := newRefreshToken(user.UserID, stored.FamilyID)
+
+		// Only one concurrent rotation of the same token may win.
+		res := tx.Model(&stored).Where("revoked_at IS NULL").Updates(map[string]interface{}{
+			"revoked_at":  time.Now(),
+			"replaced_by": next.TokenID,
+		})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		if err := tx.Create(next).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&dbmodel.Session{}).Where("id = ?", stored.FamilyID).Updates(map[string]interface{}{
+			"last_seen_at": time.Now(),
+			"ip_address":   auth.GetClientIPFromContext(ctx),
+			"user_agent":   auth.GetUserAgentFromContext(ctx),
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		payload, err = signTokenPair(&user, next)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenReused) {
+		if err := r.endSession(ctx, stored.FamilyID); err != nil {
+			return nil, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		return nil, errRefreshTokenReused
+	}
 	if err != nil {
-		return nil, errors.New("failed to generate token")
+		return nil, err
 	}
 
-	return &gqlmodel.AuthPayload{
-		Token: token,
-		User:  &user,
-	}, nil
+	r.Audit.Record(ctx, audit.TokenRefreshed, user.UserID, user.Email, "")
+
+	return payload, nil
 }
 
 func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
-	// JWT is stateless → client just deletes token (e.g. from localStorage or cookie)
+	claims, err := auth.GetClaimsFromContext(ctx)
+	if err != nil {
+		return false, errors.New("unauthenticated")
+	}
+
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := r.Revocations.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return false, fmt.Errorf("failed to revoke token: %w", err)
+		}
+	}
+
+	// Also end the session so it can't be renewed.
+	if claims.FamilyID != "" {
+		if err := r.endSession(ctx, claims.FamilyID); err != nil {
+			return false, fmt.Errorf("failed to revoke token family: %w", err)
+		}
+	}
+
+	r.Audit.Record(ctx, audit.Logout, claims.UserID, "", "")
+
 	return true, nil
 }
 
-func (r *queryResolver) FetchUsers(ctx context.Context) ([]*dbmodel.User, error) {
-	_, err := auth.GetUserIDFromContext(ctx)
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
+	// Always report success so the mutation can't be used to probe for accounts
+	var user dbmodel.User
+	if err := r.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return true, nil
+	}
+	// Invited users set their password by accepting the invitation
+	if user.Status != dbmodel.UserStatusActive {
+		return true, nil
+	}
+
+	token, hash, err := auth.NewOpaqueToken()
 	if err != nil {
-		return nil, errors.New("unauthenticated")
+		return false, errors.New("failed to generate reset token")
 	}
 
-	var users []*dbmodel.User
-	if err := r.DB.Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		// Only the most recent link stays valid
+		if err := tx.Model(&dbmodel.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", user.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+		return tx.Create(&dbmodel.PasswordResetToken{
+			UserID:    user.UserID,
+			TokenHash: hash,
+			ExpiresAt: time.Now().Add(passwordResetTTL),
+		}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to create reset token: %w", err)
 	}
 
-	return users, nil
+	msg := mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %d minutes.\n\n%s/reset-password?token=%s\n\nIf you didn't ask for this, you can ignore this email.\n",
+			user.Username, int(passwordResetTTL.Minutes()), r.FrontendURL, token),
+	}
+	if err := r.Mailer.Send(ctx, msg); err != nil {
+		slog.Error("Failed to send password reset email", "userId", user.UserID, "error", err)
+	}
+
+	return true, nil
 }
 
+func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newPassword string) (bool, error) {
+	var reset dbmodel.PasswordResetToken
+	err := r.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashOpaqueToken(token), time.Now()).
+		First(&reset).Error
+	if err != nil {
+		return false, errors.New("invalid or expired reset token")
+	}
 
-// Mutation returns generated.MutationResolver implementation.
-func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", reset.UserID).First(&user).Error; err != nil {
+		return false, errors.New("invalid or expired reset token")
+	}
+	if err := account.CheckNewPassword(r.DB, r.Passwords, &user, newPassword); err != nil {
+		return false, err
+	}
 
-// Query returns generated.QueryResolver implementation.
-func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		// Consume the token first so concurrent requests can't both use it
+		res := tx.Model(&reset).Where("used_at IS NULL").Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("invalid or expired reset token")
+		}
+
+		if err := account.SetPassword(tx, r.Passwords, &user, newPassword); err != nil {
+			return err
+		}
+		return account.RevokeTokens(tx, user.UserID)
+	})
+	if err != nil {
+		return false, err
+	}
+
+	// Sign out everywhere, whoever knew the old password
+	if err := r.Revocations.RevokeUser(ctx, user.UserID); err != nil {
+		return false, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	// Proving access to the mailbox is enough to lift a lockout
+	if err := r.LoginLimiter.Unlock(ctx, user.Email); err != nil {
+		slog.Error("Failed to unlock user after password reset", "userId", user.UserID, "error", err)
+	}
+
+	r.Audit.Record(ctx, audit.PasswordChanged, user.UserID, user.Email, "reset")
+
+	return true, nil
+}
+
+func (r *mutationResolver) ChangePassword(ctx context.Context, oldPassword string, newPassword string) (bool, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, errors.New("unauthorized")
+	}
+	if err := forbidImpersonation(ctx); err != nil {
+		return false, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return false, errors.New("user not found")
+	}
+
+	if !r.Passwords.Verify(oldPassword, user.PasswordHash) {
+		return false, errors.New("current password is incorrect")
+	}
+	if err := account.CheckNewPassword(r.DB, r.Passwords, &user, newPassword); err != nil {
+		return false, err
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := account.SetPassword(tx, r.Passwords, &user, newPassword); err != nil {
+			return err
+		}
+		return account.RevokeTokens(tx, user.UserID)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to change password: %w", err)
+	}
+
+	// Every session, the current one included, has to log in again
+	if err := r.Revocations.RevokeUser(ctx, user.UserID); err != nil {
+		return false, fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	r.Audit.Record(ctx, audit.PasswordChanged, user.UserID, user.Email, "changed")
+
+	return true, nil
+}
+
+func (r *mutationResolver) UpdateMyProfile(ctx context.Context, input gqlmodel.UpdateProfileInput) (*dbmodel.User, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	updates, err := profileUpdates(input)
+	if err != nil {
+		return nil, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if len(updates) == 0 {
+		return &user, nil
+	}
+
+	if err := r.DB.Model(&user).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	if err := r.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	r.Events.Publish(ctx, events.UserUpdated, &user, userID)
+
+	return &user, nil
+}
+
+func (r *mutationResolver) SetPreference(ctx context.Context, key string, typeArg gqlmodel.PreferenceType, value any) (*gqlmodel.Preference, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthorized")
+	}
+
+	if !preferenceKeyPattern.MatchString(key) {
+		return nil, errors.New("preference keys are 1 to 64 letters, digits or . _ : -")
+	}
+	encoded, err := encodePreference(typeArg, value)
+	if err != nil {
+		return nil, err
+	}
+
+	pref := dbmodel.UserPreference{
+		UserID: userID,
+		Key:    key,
+		Type:   string(typeArg),
+		Value:  encoded,
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&dbmodel.UserPreference{}).
+			Where("user_id = ? AND key <> ?", userID, key).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count >= maxPreferencesPerUser {
+			return fmt.Errorf("at most %d preferences can be stored", maxPreferencesPerUser)
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"type", "value", "updated_at"}),
+		}).Create(&pref).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return toPreference(&pref)
+}
+
+func (r *mutationResolver) DeletePreference(ctx context.Context, key string) (bool, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return false, errors.New("unauthorized")
+	}
+
+	res := r.DB.Where("user_id = ? AND key = ?", userID, key).Delete(&dbmodel.UserPreference{})
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to delete preference: %w", res.Error)
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *mutationResolver) VerifyEmail(ctx context.Context, token string) (bool, error) {
+	var verification dbmodel.EmailVerificationToken
+	err := r.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashOpaqueToken(token), time.Now()).
+		First(&verification).Error
+	if err != nil {
+		return false, errors.New("invalid or expired verification token")
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&verification).Where("used_at IS NULL").Update("used_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("invalid or expired verification token")
+		}
+
+		// The link only proves the address it was sent to
+		res = tx.Model(&dbmodel.User{}).
+			Where("user_id = ? AND email = ?", verification.UserID, verification.Email).
+			Update("email_verified", true)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("invalid or expired verification token")
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) RequestEmailVerification(ctx context.Context, email string) (bool, error) {
+	// Always report success so the mutation can't be used to probe for accounts
+	var user dbmodel.User
+	if err := r.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return true, nil
+	}
+	if user.Status != dbmodel.UserStatusActive || user.EmailVerified {
+		return true, nil
+	}
+
+	if err := r.sendEmailVerification(ctx, &user); err != nil {
+		slog.Error("Failed to send email verification", "userId", user.UserID, "error", err)
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) VerifyMfa(ctx context.Context, mfaToken string, code string) (*gqlmodel.AuthPayload, error) {
+	claims, err := r.parseMFAToken(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Status == dbmodel.UserStatusDeactivated {
+		return nil, errors.New("account is deactivated")
+	}
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	err = r.LoginLimiter.Check(ctx, user.Email, auth.GetClientIPFromContext(ctx))
+	var blocked *auth.LoginBlockedError
+	if errors.As(err, &blocked) {
+		r.Audit.Record(ctx, audit.LoginFailed, user.UserID, user.Email, blocked.Code)
+		return nil, loginBlockedError(blocked)
+	}
+	if err != nil {
+		return nil, errors.New("login is temporarily unavailable")
+	}
+
+	if err := r.verifySecondFactor(&user, code); err != nil {
+		if errors.Is(err, errInvalidMFACode) {
+			return nil, r.loginFailed(ctx, user.Email, errInvalidMFACode)
+		}
+		return nil, fmt.Errorf("failed to verify code: %w", err)
+	}
+
+	if err := r.consumeMFAToken(ctx, claims); err != nil {
+		return nil, fmt.Errorf("failed to consume mfa token: %w", err)
+	}
+	if err := r.LoginLimiter.Reset(ctx, user.Email); err != nil {
+		slog.Error("Failed to reset failed logins", "userId", user.UserID, "error", err)
+	}
+
+	return r.startSession(ctx, &user, "second factor")
+}
+
+func (r *mutationResolver) EnrollTotp(ctx context.Context, mfaToken *string) (*gqlmodel.TotpEnrollment, error) {
+	user, _, err := r.totpUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, sealed, err := r.TOTP.NewSecret()
+	if err != nil {
+		return nil, errors.New("failed to generate secret")
+	}
+	// Starting over replaces an enrollment that was never confirmed
+	if err := r.DB.Model(user).Updates(map[string]interface{}{
+		"totp_secret":    sealed,
+		"totp_last_step": 0,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to store secret: %w", err)
+	}
+
+	return &gqlmodel.TotpEnrollment{
+		Secret:     secret,
+		OtpauthURI: r.TOTP.URI(user.Email, secret),
+	}, nil
+}
+
+func (r *mutationResolver) ConfirmTotp(ctx context.Context, code string, mfaToken *string) (*gqlmodel.TotpConfirmation, error) {
+	user, claims, err := r.totpUser(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, errors.New("call enrollTotp first")
+	}
+
+	var codes []string
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := r.useTOTPCode(tx, user, code); err != nil {
+			return err
+		}
+		if err := tx.Model(user).Update("totp_enabled", true).Error; err != nil {
+			return err
+		}
+		codes, err = replaceRecoveryCodes(tx, user.UserID)
+		return err
+	})
+	if errors.Is(err, errInvalidMFACode) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	result := &gqlmodel.TotpConfirmation{RecoveryCodes: codes}
+	if claims != nil {
+		// Enrollment was the missing step of a login
+		if err := r.consumeMFAToken(ctx, claims); err != nil {
+			return nil, fmt.Errorf("failed to consume mfa token: %w", err)
+		}
+		if err := r.LoginLimiter.Reset(ctx, user.Email); err != nil {
+			slog.Error("Failed to reset failed logins", "userId", user.UserID, "error", err)
+		}
+		if result.Auth, err = r.startSession(ctx, user, "second factor"); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (r *mutationResolver) DisableTotp(ctx context.Context, code string) (bool, error) {
+	user, _, err := r.totpUser(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	if !user.TOTPEnabled {
+		return false, errors.New("two-factor authentication is not enabled")
+	}
+	if r.requiresMFA(user) {
+		return false, fmt.Errorf("two-factor authentication is required for role %s", user.Role)
+	}
+
+	if err := r.verifySecondFactor(user, code); err != nil {
+		return false, err
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"totp_secret":    "",
+			"totp_enabled":   false,
+			"totp_last_step": 0,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.UserID).Delete(&dbmodel.RecoveryCode{}).Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) RegenerateRecoveryCodes(ctx context.Context, code string) ([]string, error) {
+	user, _, err := r.totpUser(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := r.verifySecondFactor(user, code); err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		codes, err = replaceRecoveryCodes(tx, user.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate recovery codes: %w", err)
+	}
+
+	return codes, nil
+}
+
+func (r *mutationResolver) CreateServiceAccount(ctx context.Context, name string, scopes []string) (*gqlmodel.ServiceAccountCredentials, error) {
+	callerID, err := requireRole(ctx, dbmodel.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("name cannot be empty")
+	}
+	scopes, err = normalizeScopes(scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	var existing dbmodel.ServiceAccount
+	if err := r.DB.Where("name = ?", name).First(&existing).Error; err == nil {
+		return nil, errors.New("service account name already in use")
+	}
+
+	account := &dbmodel.ServiceAccount{
+		Name:      name,
+		Scopes:    strings.Join(scopes, " "),
+		CreatedBy: callerID,
+	}
+	var key string
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return err
+		}
+		key, err = issueAPIKey(tx, account)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create service account: %w", err)
+	}
+
+	return r.serviceAccountCredentials(account, key)
+}
+
+func (r *mutationResolver) RotateAPIKey(ctx context.Context, serviceAccountID string) (*gqlmodel.ServiceAccountCredentials, error) {
+	if _, err := requireRole(ctx, dbmodel.RoleAdmin); err != nil {
+		return nil, err
+	}
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	var account dbmodel.ServiceAccount
+	if err := r.DB.Where("id = ? AND disabled_at IS NULL", serviceAccountID).First(&account).Error; err != nil {
+		return nil, errors.New("service account not found")
+	}
+
+	var key string
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		graceEnd := time.Now().Add(apiKeyRotationGrace)
+		if err := tx.Model(&dbmodel.APIKey{}).
+			Where("service_account_id = ? AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", account.ID, graceEnd).
+			Update("expires_at", graceEnd).Error; err != nil {
+			return err
+		}
+		var err error
+		key, err = issueAPIKey(tx, &account)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate api key: %w", err)
+	}
+
+	return r.serviceAccountCredentials(&account, key)
+}
+
+func (r *mutationResolver) RevokeAPIKey(ctx context.Context, id string) (bool, error) {
+	if _, err := requireRole(ctx, dbmodel.RoleAdmin); err != nil {
+		return false, err
+	}
+
+	res := r.DB.Model(&dbmodel.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to revoke api key: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return false, errors.New("api key not found")
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) DisableServiceAccount(ctx context.Context, id string) (bool, error) {
+	if _, err := requireRole(ctx, dbmodel.RoleAdmin); err != nil {
+		return false, err
+	}
+
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&dbmodel.ServiceAccount{}).
+			Where("id = ? AND disabled_at IS NULL", id).
+			Update("disabled_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("service account not found")
+		}
+		return tx.Model(&dbmodel.APIKey{}).
+			Where("service_account_id = ? AND revoked_at IS NULL", id).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) RevokeSession(ctx context.Context, id string) (bool, error) {
+	claims, err := auth.GetClaimsFromContext(ctx)
+	if err != nil {
+		return false, errors.New("unauthenticated")
+	}
+	if err := forbidImpersonation(ctx); err != nil {
+		return false, err
+	}
+
+	var session dbmodel.Session
+	if err := r.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, claims.UserID).First(&session).Error; err != nil {
+		return false, errors.New("session not found")
+	}
+
+	if err := r.endSession(ctx, session.ID); err != nil {
+		return false, fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) RevokeAllOtherSessions(ctx context.Context) (bool, error) {
+	claims, err := auth.GetClaimsFromContext(ctx)
+	if err != nil {
+		return false, errors.New("unauthenticated")
+	}
+	if err := forbidImpersonation(ctx); err != nil {
+		return false, err
+	}
+
+	// Token families are listed rather than sessions so logins from before
+	// sessions were recorded are ended too
+	query := r.DB.Model(&dbmodel.RefreshToken{}).Where("user_id = ? AND revoked_at IS NULL", claims.UserID)
+	if claims.FamilyID != "" {
+		query = query.Where("family_id <> ?", claims.FamilyID)
+	}
+	var familyIDs []string
+	err = query.Distinct().Pluck("family_id", &familyIDs).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+
+	for _, familyID := range familyIDs {
+		if err := r.endSession(ctx, familyID); err != nil {
+			return false, fmt.Errorf("failed to revoke session: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) InviteUser(ctx context.Context, email string, role string) (*dbmodel.Invitation, error) {
+	callerID, err := requireManager(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCanAssignRole(ctx, role); err != nil {
+		return nil, err
+	}
+	if !isEmailAddress(email) {
+		return nil, errors.New("invalid email address")
+	}
+
+	// Inviting a pending user again replaces their invitation, e.g. to resend it
+	var user dbmodel.User
+	if err := r.DB.Where("email = ?", email).First(&user).Error; err == nil {
+		if user.Status != dbmodel.UserStatusPending {
+			return nil, errors.New("email already in use")
+		}
+		if err := checkCanManage(ctx, &user); err != nil {
+			return nil, err
+		}
+	} else {
+		user = dbmodel.User{
+			Email:  email,
+			Role:   role,
+			Status: dbmodel.UserStatusPending,
+		}
+	}
+
+	invitation := &dbmodel.Invitation{
+		Email:     email,
+		Role:      role,
+		InvitedBy: callerID,
+		ExpiresAt: time.Now().Add(invitationTTL),
+	}
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		if user.UserID == "" {
+			if err := tx.Create(&user).Error; err != nil {
+				return err
+			}
+		} else {
+			if err := tx.Model(&user).Update("role", role).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&dbmodel.Invitation{}).
+				Where("user_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", user.UserID).
+				Update("revoked_at", time.Now()).Error; err != nil {
+				return err
+			}
+		}
+
+		invitation.UserID = user.UserID
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	if err := r.sendInvitation(ctx, invitation); err != nil {
+		return nil, fmt.Errorf("failed to send invitation: %w", err)
+	}
+
+	return invitation, nil
+}
+
+func (r *mutationResolver) AcceptInvitation(ctx context.Context, token string, username string, password string) (*dbmodel.User, error) {
+	claims, err := auth.ParseInvitationToken(token)
+	if err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	var invitation dbmodel.Invitation
+	err = r.DB.Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL AND expires_at > ?", claims.ID, time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ? AND status = ?", invitation.UserID, dbmodel.UserStatusPending).First(&user).Error; err != nil {
+		return nil, errors.New("invalid or expired invitation")
+	}
+
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, errors.New("username is required")
+	}
+	if err := r.Passwords.Validate(password); err != nil {
+		return nil, err
+	}
+
+	hash, err := r.Passwords.Hash(password)
+	if err != nil {
+		return nil, errors.New("failed to hash password")
+	}
+
+	err = r.DB.Transaction(func(tx *gorm.DB) error {
+		// Consume the invitation first so concurrent requests can't both use it
+		res := tx.Model(&invitation).Where("accepted_at IS NULL AND revoked_at IS NULL").Update("accepted_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("invalid or expired invitation")
+		}
+
+		// The invitation was emailed, so following it verifies the address it
+		// was sent to
+		user.Username = username
+		user.PasswordHash = hash
+		user.Status = dbmodel.UserStatusActive
+		user.EmailVerified = invitation.Email == user.Email
+		return tx.Select("username", "password_hash", "status", "email_verified").Updates(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	r.Events.Publish(ctx, events.UserCreated, &user, invitation.InvitedBy)
+
+	return &user, nil
+}
+
+func (r *mutationResolver) RevokeInvitation(ctx context.Context, id string) (bool, error) {
+	if _, err := requireManager(ctx); err != nil {
+		return false, err
+	}
+
+	var invitation dbmodel.Invitation
+	if err := r.DB.Where("id = ? AND accepted_at IS NULL AND revoked_at IS NULL", id).First(&invitation).Error; err != nil {
+		return false, errors.New("invitation not found")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", invitation.UserID).First(&user).Error; err != nil {
+		return false, errors.New("invitation not found")
+	}
+	if err := checkCanManage(ctx, &user); err != nil {
+		return false, err
+	}
+
+	// The pending user only existed for the invitation, so the email is free
+	// to be invited again
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&invitation).Where("accepted_at IS NULL AND revoked_at IS NULL").Update("revoked_at", time.Now())
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errors.New("invitation not found")
+		}
+		return tx.Where("user_id = ? AND status = ?", user.UserID, dbmodel.UserStatusPending).Delete(&dbmodel.User{}).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) RequestMagicLink(ctx context.Context, email string) (bool, error) {
+	// Counted whether or not the email has an account, so the limit doesn't
+	// reveal it either
+	allowed, retryAfter, err := r.MagicLinkLimiter.Allow(ctx, email)
+	if err != nil {
+		return false, errors.New("login links are temporarily unavailable")
+	}
+	if !allowed {
+		return false, tooManyMagicLinksError(retryAfter)
+	}
+
+	// Always report success so the mutation can't be used to probe for
+	// accounts or their role
+	var user dbmodel.User
+	if err := r.DB.Where("email = ?", email).First(&user).Error; err != nil {
+		return true, nil
+	}
+	if user.Status != dbmodel.UserStatusActive {
+		return true, nil
+	}
+	enabled, err := r.magicLinkEnabled(user.Role)
+	if err != nil {
+		return false, fmt.Errorf("failed to load role settings: %w", err)
+	}
+	if !enabled {
+		return true, nil
+	}
+
+	if err := r.sendMagicLink(ctx, &user); err != nil {
+		slog.Error("Failed to send login link", "userId", user.UserID, "error", err)
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) ConsumeMagicLink(ctx context.Context, token string) (*gqlmodel.AuthPayload, error) {
+	var link dbmodel.MagicLinkToken
+	err := r.DB.Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", auth.HashOpaqueToken(token), time.Now()).
+		First(&link).Error
+	if err != nil {
+		return nil, errInvalidMagicLink
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", link.UserID).First(&user).Error; err != nil {
+		return nil, errInvalidMagicLink
+	}
+	if user.Status != dbmodel.UserStatusActive {
+		return nil, errors.New("account is deactivated")
+	}
+	// The role may have changed, or the switch been turned off, since the
+	// link was sent
+	enabled, err := r.magicLinkEnabled(user.Role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load role settings: %w", err)
+	}
+	if !enabled {
+		return nil, errors.New("login links are disabled for your role")
+	}
+	if r.RequireVerifiedEmail && !user.EmailVerified {
+		return nil, emailNotVerifiedError()
+	}
+
+	// Consume the link so concurrent requests can't both use it
+	res := r.DB.Model(&link).Where("used_at IS NULL").Update("used_at", time.Now())
+	if res.Error != nil {
+		return nil, fmt.Errorf("failed to consume login link: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return nil, errInvalidMagicLink
+	}
+
+	// The link replaces the password, not the second factor
+	if user.TOTPEnabled {
+		return mfaChallenge(&user, false)
+	}
+	if r.requiresMFA(&user) {
+		return mfaChallenge(&user, true)
+	}
+
+	if err := r.LoginLimiter.Reset(ctx, user.Email); err != nil {
+		slog.Error("Failed to reset failed logins", "userId", user.UserID, "error", err)
+	}
+
+	return r.startSession(ctx, &user, "magic link")
+}
+
+func (r *mutationResolver) SetMagicLinkEnabled(ctx context.Context, role string, enabled bool) (bool, error) {
+	callerID, err := requireRole(ctx, dbmodel.RoleAdmin)
+	if err != nil {
+		return false, err
+	}
+	if !dbmodel.IsValidRole(role) {
+		return false, errors.New("invalid role")
+	}
+
+	setting := dbmodel.RoleSetting{
+		Role:             role,
+		MagicLinkEnabled: enabled,
+		UpdatedBy:        callerID,
+	}
+	if err := r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "role"}},
+		DoUpdates: clause.AssignmentColumns([]string{"magic_link_enabled", "updated_by", "updated_at"}),
+	}).Create(&setting).Error; err != nil {
+		return false, fmt.Errorf("failed to update role settings: %w", err)
+	}
+
+	return true, nil
+}
+
+func (r *mutationResolver) Impersonate(ctx context.Context, userID string, reason string) (*gqlmodel.ImpersonationPayload, error) {
+	actorID, err := requireRole(ctx, dbmodel.RoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if err := forbidImpersonation(ctx); err != nil {
+		return nil, err
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required")
+	}
+	if len(reason) > maxImpersonationReason {
+		return nil, fmt.Errorf("reason must be at most %d characters", maxImpersonationReason)
+	}
+	if userID == actorID {
+		return nil, errors.New("cannot impersonate yourself")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+	if user.Role == dbmodel.RoleAdmin {
+		return nil, errors.New("admins can't be impersonated")
+	}
+	if user.Status != dbmodel.UserStatusActive {
+		return nil, errors.New("only active users can be impersonated")
+	}
+
+	expiresAt := time.Now().Add(auth.ImpersonationTokenTTL)
+	token, err := auth.GenerateImpersonationToken(user.UserID, user.Role, actorID, expiresAt)
+	if err != nil {
+		return nil, errors.New("failed to generate token")
+	}
+
+	r.Audit.RecordImpersonation(ctx, actorID, &user, reason)
+	slog.Info("Impersonation started", "actorId", actorID, "userId", user.UserID, "reason", reason)
+
+	return &gqlmodel.ImpersonationPayload{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User:      &user,
+	}, nil
+}
+
+func (r *queryResolver) FetchUsers(ctx context.Context) ([]*dbmodel.User, error) {
+	if err := requireScope(ctx, auth.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	var users []*dbmodel.User
+	if err := r.DB.Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+
+	return users, nil
+}
+
+func (r *queryResolver) User(ctx context.Context, id string) (*dbmodel.User, error) {
+	if err := requireScope(ctx, auth.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	user, err := loader.For(ctx).Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %w", err)
+	}
+
+	return user, nil
+}
+
+func (r *queryResolver) UsersByIds(ctx context.Context, ids []string) ([]*dbmodel.User, error) {
+	if err := requireScope(ctx, auth.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	found, err := loader.For(ctx).LoadAll(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+
+	// Unknown IDs are skipped, the rest keep the requested order
+	users := make([]*dbmodel.User, 0, len(found))
+	for _, user := range found {
+		if user != nil {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *queryResolver) Users(ctx context.Context, filter *gqlmodel.UserFilter, first *int, after *string) (*gqlmodel.UserConnection, error) {
+	if err := requireScope(ctx, auth.ScopeUsersRead); err != nil {
+		return nil, err
+	}
+
+	limit := defaultPageSize
+	if first != nil {
+		limit = *first
+	}
+	if limit <= 0 || limit > maxPageSize {
+		return nil, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+	}
+
+	query := r.DB.Model(&dbmodel.User{})
+	if filter != nil {
+		if filter.Role != nil {
+			query = query.Where("role = ?", *filter.Role)
+		}
+		if filter.Email != nil {
+			query = query.Where("email ILIKE ?", "%"+database.EscapeLike(*filter.Email)+"%")
+		}
+		if filter.CreatedAfter != nil {
+			query = query.Where("created_at > ?", *filter.CreatedAfter)
+		}
+	}
+	if after != nil {
+		createdAt, userID, err := decodeUserCursor(*after)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("(created_at, user_id) > (?, ?)", createdAt, userID)
+	}
+
+	// Fetch one extra row to know whether another page exists
+	var users []*dbmodel.User
+	if err := query.Order("created_at, user_id").Limit(limit + 1).Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %w", err)
+	}
+
+	hasNextPage := len(users) > limit
+	if hasNextPage {
+		users = users[:limit]
+	}
+
+	conn := &gqlmodel.UserConnection{
+		Edges:    make([]*gqlmodel.UserEdge, 0, len(users)),
+		PageInfo: &gqlmodel.PageInfo{HasNextPage: hasNextPage},
+	}
+	for _, user := range users {
+		conn.Edges = append(conn.Edges, &gqlmodel.UserEdge{Cursor: encodeUserCursor(user), Node: user})
+	}
+	if len(conn.Edges) > 0 {
+		conn.PageInfo.EndCursor = &conn.Edges[len(conn.Edges)-1].Cursor
+	}
+
+	return conn, nil
+}
+
+func (r *queryResolver) Me(ctx context.Context) (*dbmodel.User, error) {
+	userID, err := auth.GetUserIDFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthenticated")
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", userID).First(&user).Error; err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	return &user, nil
+}
+
+func (r *queryResolver) IntrospectToken(ctx context.Context, token string) (*gqlmodel.TokenIntrospection, error) {
+	// Invalid, expired or revoked tokens are reported as inactive, not as errors
+	claims, err := auth.VerifyAccessToken(ctx, r.Revocations, token)
+	if err != nil {
+		return &gqlmodel.TokenIntrospection{Active: false}, nil
+	}
+
+	var user dbmodel.User
+	if err := r.DB.Where("user_id = ?", claims.UserID).First(&user).Error; err != nil {
+		return &gqlmodel.TokenIntrospection{Active: false}, nil
+	}
+
+	result := &gqlmodel.TokenIntrospection{
+		Active: true,
+		User:   &user,
+		Role:   &claims.Role,
+	}
+	if claims.ExpiresAt != nil {
+		result.ExpiresAt = &claims.ExpiresAt.Time
+	}
+
+	return result, nil
+}
+
+func (r *queryResolver) ServiceAccounts(ctx context.Context) ([]*gqlmodel.ServiceAccount, error) {
+	if _, err := requireRole(ctx, dbmodel.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	var accounts []dbmodel.ServiceAccount
+	if err := r.DB.Order("name").Find(&accounts).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch service accounts: %w", err)
+	}
+
+	return r.loadServiceAccounts(accounts)
+}
+
+func (r *queryResolver) MySessions(ctx context.Context) ([]*gqlmodel.Session, error) {
+	claims, err := auth.GetClaimsFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthenticated")
+	}
+
+	var sessions []dbmodel.Session
+	err = activeSessions(r.DB).Where("user_id = ?", claims.UserID).
+		Order("last_seen_at DESC").Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+
+	result := make([]*gqlmodel.Session, len(sessions))
+	for i := range sessions {
+		result[i] = toSession(&sessions[i], claims.FamilyID)
+	}
+	return result, nil
+}
+
+func (r *queryResolver) Invitations(ctx context.Context) ([]*dbmodel.Invitation, error) {
+	if _, err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+
+	var invitations []*dbmodel.Invitation
+	if err := r.DB.Where("accepted_at IS NULL AND revoked_at IS NULL").Order("created_at DESC").Find(&invitations).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch invitations: %w", err)
+	}
+
+	return invitations, nil
+}
+
+func (r *queryResolver) MagicLinkRoles(ctx context.Context) ([]string, error) {
+	if _, err := requireRole(ctx, dbmodel.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	roles := []string{}
+	if err := r.DB.Model(&dbmodel.RoleSetting{}).
+		Where("magic_link_enabled").
+		Order("role").
+		Pluck("role", &roles).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch role settings: %w", err)
+	}
+
+	return roles, nil
+}
+
+func (r *queryResolver) AuthEvents(ctx context.Context, userID *string, from *time.Time, to *time.Time) ([]*dbmodel.AuthEvent, error) {
+	if _, err := requireRole(ctx, dbmodel.RoleAdmin); err != nil {
+		return nil, err
+	}
+
+	query := r.DB.Order("created_at DESC").Limit(maxAuthEvents)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	if from != nil {
+		query = query.Where("created_at >= ?", *from)
+	}
+	if to != nil {
+		query = query.Where("created_at < ?", *to)
+	}
+
+	events := []*dbmodel.AuthEvent{}
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch auth events: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *subscriptionResolver) UserChanged(ctx context.Context, filter *gqlmodel.UserChangeFilter) (<-chan *pubsub.UserChange, error) {
+	if _, err := requireManager(ctx); err != nil {
+		return nil, err
+	}
+	claims, err := auth.GetClaimsFromContext(ctx)
+	if err != nil {
+		return nil, errors.New("unauthenticated")
+	}
+
+	changes, unsubscribe := r.UserChanges.Subscribe()
+	out := make(chan *pubsub.UserChange, 1)
+	go func() {
+		defer close(out)
+		defer unsubscribe()
+
+		// End the subscription with the access token it was authorized with
+		var expired <-chan time.Time
+		if claims.ExpiresAt != nil {
+			timer := time.NewTimer(time.Until(claims.ExpiresAt.Time))
+			defer timer.Stop()
+			expired = timer.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-expired:
+				return
+			case change := <-changes:
+				if !matchesUserChangeFilter(change, filter) {
+					continue
+				}
+				// Logout, session revocation, deactivation and role changes
+				// all revoke the token, so stop streaming once it is
+				if err := auth.CheckAccessToken(ctx, r.Revocations, claims); err != nil {
+					return
+				}
+				select {
+				case out <- change:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// Mutation returns generated.MutationResolver implementation.
+func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
+
+// Query returns generated.QueryResolver implementation.
+func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
+
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
 
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
@@ -0,0 +1,35 @@
+package account
+
+import (
+	"user-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ownedByUser are the rows that only make sense with their user. There are no
+// foreign keys, so they are deleted along with it; the auth events stay as the
+// audit trail.
+var ownedByUser = []interface{}{
+	&model.RefreshToken{},
+	&model.Session{},
+	&model.PasswordResetToken{},
+	&model.EmailVerificationToken{},
+	&model.MagicLinkToken{},
+	&model.Invitation{},
+	&model.PasswordHistory{},
+	&model.RecoveryCode{},
+	&model.UserPreference{},
+}
+
+// Delete deletes user and everything it owns in one transaction, for the
+// deleteUser mutation and SCIM deprovisioning alike.
+func Delete(db *gorm.DB, user *model.User) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, rows := range ownedByUser {
+			if err := tx.Where("user_id = ?", user.UserID).Delete(rows).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(user).Error
+	})
+}
@@ -0,0 +1,62 @@
+package account
+
+import (
+	"user-service/internal/model"
+	"user-service/internal/password"
+
+	"gorm.io/gorm"
+)
+
+// CheckNewPassword applies policy to a password user wants to switch to,
+// including reuse of the current or a recent password.
+func CheckNewPassword(db *gorm.DB, policy *password.Policy, user *model.User, newPassword string) error {
+	if err := policy.Validate(newPassword); err != nil {
+		return err
+	}
+
+	previous := []string{user.PasswordHash}
+	var history []model.PasswordHistory
+	if err := db.Where("user_id = ?", user.UserID).
+		Order("created_at DESC").
+		Limit(policy.HistorySize).
+		Find(&history).Error; err != nil {
+		return err
+	}
+	for _, h := range history {
+		previous = append(previous, h.PasswordHash)
+	}
+
+	return policy.CheckReuse(newPassword, previous)
+}
+
+// SetPassword replaces the password of user inside tx, keeping the old hash in
+// the history and pruning entries the policy no longer looks at. Callers also
+// revoke the user's tokens.
+func SetPassword(tx *gorm.DB, policy *password.Policy, user *model.User, newPassword string) error {
+	hash, err := policy.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Create(&model.PasswordHistory{
+		UserID:       user.UserID,
+		PasswordHash: user.PasswordHash,
+	}).Error; err != nil {
+		return err
+	}
+	// The current hash counts towards HistorySize, so keep one less
+	prune := tx.Where("user_id = ?", user.UserID)
+	if keep := policy.HistorySize - 1; keep > 0 {
+		prune = prune.Where("id NOT IN (?)", tx.Model(&model.PasswordHistory{}).
+			Select("id").
+			Where("user_id = ?", user.UserID).
+			Order("created_at DESC").
+			Limit(keep))
+	}
+	if err := prune.Delete(&model.PasswordHistory{}).Error; err != nil {
+		return err
+	}
+
+	user.PasswordHash = hash
+	return tx.Model(user).Update("password_hash", user.PasswordHash).Error
+}
@@ -0,0 +1,24 @@
+package account
+
+import (
+	"time"
+
+	"user-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// RevokeTokens revokes every still-active refresh token of a user and ends
+// all of their sessions, on deactivation and password changes. Their access
+// tokens are revoked separately, in Redis.
+func RevokeTokens(db *gorm.DB, userID string) error {
+	now := time.Now()
+	if err := db.Model(&model.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error; err != nil {
+		return err
+	}
+	return db.Model(&model.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now).Error
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"user-service/internal/auth"
+	"user-service/internal/messaging"
+	"user-service/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// Types of model.AuthEvent
+const (
+	LoginSucceeded  = "LOGIN_SUCCEEDED"
+	LoginFailed     = "LOGIN_FAILED"
+	Logout          = "LOGOUT"
+	TokenRefreshed  = "TOKEN_REFRESHED"
+	PasswordChanged = "PASSWORD_CHANGED"
+	// ImpersonationStarted is recorded for the impersonated user, with the
+	// admin as actor and their reason as detail
+	ImpersonationStarted = "IMPERSONATION_STARTED"
+)
+
+// NewDeviceLogin is published to the user.notifications topic when a user
+// logs in from a device never seen for them.
+const NewDeviceLogin = "NEW_DEVICE_LOGIN"
+
+// Trail records authentication events with the client IP and user agent of
+// the request, and notifies users of logins from new devices.
+type Trail struct {
+	db            *gorm.DB
+	notifications *messaging.KafkaProducer
+}
+
+func NewTrail(db *gorm.DB, notifications *messaging.KafkaProducer) *Trail {
+	return &Trail{db: db, notifications: notifications}
+}
+
+// Record stores an event of the request in ctx. When userID is empty the user
+// is looked up by email. Requests made with an impersonation token are
+// recorded with the admin as actor. Like events, recording is best effort and
+// never fails the request.
+func (t *Trail) Record(ctx context.Context, eventType, userID, email, detail string) {
+	actorID, _ := auth.GetActorFromContext(ctx)
+	t.record(ctx, eventType, userID, actorID, email, detail)
+}
+
+// RecordImpersonation records that the admin actorID started impersonating
+// user for reason.
+func (t *Trail) RecordImpersonation(ctx context.Context, actorID string, user *model.User, reason string) {
+	t.record(ctx, ImpersonationStarted, user.UserID, actorID, user.Email, reason)
+}
+
+func (t *Trail) record(ctx context.Context, eventType, userID, actorID, email, detail string) {
+	event := &model.AuthEvent{
+		Email:     email,
+		Type:      eventType,
+		Detail:    detail,
+		IPAddress: auth.GetClientIPFromContext(ctx),
+		UserAgent: auth.GetUserAgentFromContext(ctx),
+	}
+	if actorID != "" {
+		event.ActorID = &actorID
+	}
+	if userID == "" && email != "" {
+		var user model.User
+		if err := t.db.Select("user_id").Where("email = ?", email).First(&user).Error; err == nil {
+			userID = user.UserID
+		}
+	}
+	if userID != "" {
+		event.UserID = &userID
+	}
+
+	if err := t.db.Create(event).Error; err != nil {
+		slog.Error("Failed to record auth event", "type", eventType, "userId", userID, "error", err)
+	}
+}
+
+// RecordLogin records a successful login of user, made as described by
+// detail. A device is the pair of IP and user agent; the first login of a
+// user doesn't count as a new device.
+func (t *Trail) RecordLogin(ctx context.Context, user *model.User, detail string) {
+	ip := auth.GetClientIPFromContext(ctx)
+	userAgent := auth.GetUserAgentFromContext(ctx)
+
+	var history struct {
+		Logins int64
+		Seen   bool
+	}
+	err := t.db.Model(&model.AuthEvent{}).
+		Select("COUNT(*) AS logins, COALESCE(BOOL_OR(ip_address = ? AND user_agent = ?), false) AS seen", ip, userAgent).
+		Where("user_id = ? AND type = ?", user.UserID, LoginSucceeded).
+		Scan(&history).Error
+	if err != nil {
+		slog.Error("Failed to load login history", "userId", user.UserID, "error", err)
+	}
+
+	t.Record(ctx, LoginSucceeded, user.UserID, user.Email, detail)
+
+	if err == nil && history.Logins > 0 && !history.Seen {
+		t.notifyNewDevice(ctx, user, ip, userAgent)
+	}
+}
+
+func (t *Trail) notifyNewDevice(ctx context.Context, user *model.User, ip, userAgent string) {
+	event := map[string]interface{}{
+		"eventType": NewDeviceLogin,
+		"userId":    user.UserID,
+		"username":  user.Username,
+		"email":     user.Email,
+		"ipAddress": ip,
+		"userAgent": userAgent,
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}
+	if err := t.notifications.Publish(ctx, user.UserID, event); err != nil {
+		slog.Error("Failed to publish new device notification", "userId", user.UserID, "error", err)
+	}
+}
@@ -3,14 +3,17 @@ package auth
 import (
 	"context"
 	"errors"
-	"fmt"
 )
 
 type contextKey string
 
 const (
-	userIDKey = contextKey("userID")
-	roleKey   = contextKey("role")
+	userIDKey    = contextKey("userID")
+	roleKey      = contextKey("role")
+	claimsKey    = contextKey("claims")
+	clientIPKey  = contextKey("clientIP")
+	serviceKey   = contextKey("servicePrincipal")
+	userAgentKey = contextKey("userAgent")
 )
 
 func WithUserID(ctx context.Context, userID string) context.Context {
@@ -21,6 +24,22 @@ func WithRole(ctx context.Context, role string) context.Context {
 	return context.WithValue(ctx, roleKey, role)
 }
 
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+func WithUserAgent(ctx context.Context, userAgent string) context.Context {
+	return context.WithValue(ctx, userAgentKey, userAgent)
+}
+
+func WithServicePrincipal(ctx context.Context, principal *ServicePrincipal) context.Context {
+	return context.WithValue(ctx, serviceKey, principal)
+}
+
 func GetUserIDFromContext(ctx context.Context) (string, error) {
 	userID, ok := ctx.Value(userIDKey).(string)
 	if !ok {
@@ -31,9 +50,47 @@ func GetUserIDFromContext(ctx context.Context) (string, error) {
 
 func GetRoleFromContext(ctx context.Context) (string, error) {
 	role, ok := ctx.Value(roleKey).(string)
-	fmt.Println("rolee", role)
 	if !ok {
 		return "", errors.New("unauthenticated")
 	}
 	return role, nil
-}
\ No newline at end of file
+}
+
+func GetClaimsFromContext(ctx context.Context) (*Claims, error) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	if !ok {
+		return nil, errors.New("unauthenticated")
+	}
+	return claims, nil
+}
+
+// GetActorFromContext returns the ID of the admin making the request when it
+// is made with an impersonation token.
+func GetActorFromContext(ctx context.Context) (string, bool) {
+	claims, ok := ctx.Value(claimsKey).(*Claims)
+	if !ok || claims.Actor == nil {
+		return "", false
+	}
+	return claims.Actor.UserID, true
+}
+
+func GetServicePrincipalFromContext(ctx context.Context) (*ServicePrincipal, error) {
+	principal, ok := ctx.Value(serviceKey).(*ServicePrincipal)
+	if !ok {
+		return nil, errors.New("unauthenticated")
+	}
+	return principal, nil
+}
+
+// GetClientIPFromContext returns the caller's IP, or "" outside of a request.
+func GetClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+// GetUserAgentFromContext returns the caller's User-Agent, or "" outside of a
+// request.
+func GetUserAgentFromContext(ctx context.Context) string {
+	userAgent, _ := ctx.Value(userAgentKey).(string)
+	return userAgent
+}
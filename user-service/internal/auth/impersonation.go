@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// LogImpersonation logs every GraphQL operation made with an impersonation
+// token with the admin behind it, so what support staff did as a user can be
+// told apart from what the user did.
+func LogImpersonation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if actorID, ok := GetActorFromContext(ctx); ok {
+		userID, _ := GetUserIDFromContext(ctx)
+		oc := graphql.GetOperationContext(ctx)
+		operationType := ""
+		if oc.Operation != nil {
+			operationType = string(oc.Operation.Operation)
+		}
+		slog.Info("Impersonated operation",
+			"actorId", actorID,
+			"userId", userID,
+			"operation", oc.OperationName,
+			"type", operationType,
+			"clientIp", GetClientIPFromContext(ctx),
+		)
+	}
+	return next(ctx)
+}
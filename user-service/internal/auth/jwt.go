@@ -6,42 +6,136 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	AccessTokenTTL  = 24 * time.Hour
+	RefreshTokenTTL = 7 * 24 * time.Hour
+	MFATokenTTL     = 5 * time.Minute
+	// ImpersonationTokenTTL is short as impersonation tokens can't be
+	// refreshed: support staff ask for a new one when it runs out.
+	ImpersonationTokenTTL = 15 * time.Minute
+
+	// refreshTokenType, mfaTokenType and invitationTokenType mark the
+	// HMAC-signed tokens so they can never be replayed as access tokens or as
+	// each other.
+	refreshTokenType    = "refresh"
+	mfaTokenType        = "mfa"
+	invitationTokenType = "invitation"
 )
 
 var (
-	accessSecret  string
+	signingKeys   *KeySet
 	refreshSecret string
 )
 
-func Init(access, refresh string) {
-	accessSecret = access
+// Init configures token signing. Access tokens are signed with the active RSA
+// key of keys so other services can verify them from the JWKS; refresh tokens
+// are only ever verified here and stay HMAC-signed with refresh.
+func Init(keys *KeySet, refresh string) {
+	signingKeys = keys
 	refreshSecret = refresh
 }
 
 type Claims struct {
-	UserID string `json:"userId"`
-	Role   string `json:"role"`
+	UserID    string `json:"userId"`
+	Role      string `json:"role"`
+	TokenType string `json:"typ,omitempty"`
+	FamilyID  string `json:"fid,omitempty"`
+	// Actor is set on access tokens of an admin impersonating UserID
+	Actor *Actor `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateAccessToken(userID string, userRole string) (string, error) {
-	fmt.Println("accessSecret =", accessSecret)
+// Actor is the act claim (RFC 8693) of an impersonation token: who is really
+// making the requests.
+type Actor struct {
+	UserID string `json:"sub"`
+}
+
+// GenerateAccessToken signs an access token with a unique jti so it can be
+// revoked on logout. familyID links it to the refresh token family it was
+// issued with.
+func GenerateAccessToken(userID string, userRole string, familyID string) (string, error) {
+	claims := Claims{
+		UserID:   userID,
+		Role:     userRole,
+		FamilyID: familyID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
+		},
+	}
+	kid, key := signingKeys.signingKey()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// GenerateImpersonationToken signs an access token letting the admin actorID
+// act as userID until expiresAt. It belongs to no session, so it can't be
+// refreshed.
+func GenerateImpersonationToken(userID, userRole, actorID string, expiresAt time.Time) (string, error) {
 	claims := Claims{
 		UserID: userID,
 		Role:   userRole,
+		Actor:  &Actor{UserID: actorID},
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	kid, key := signingKeys.signingKey()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// GenerateRefreshToken signs a refresh token whose jti is the ID of the
+// persisted model.RefreshToken row it represents.
+func GenerateRefreshToken(userID, tokenID string, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		TokenType: refreshTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        tokenID,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(accessSecret))
+	return token.SignedString([]byte(refreshSecret))
 }
 
-func GenerateRefreshToken(userID string) (string, error) {
+// GenerateMFAToken signs the short-lived challenge token a login returns when
+// the second factor is still missing.
+func GenerateMFAToken(userID string) (string, error) {
 	claims := Claims{
-		UserID: userID,
+		UserID:    userID,
+		TokenType: mfaTokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(MFATokenTTL)),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(refreshSecret))
+}
+
+// GenerateInvitationToken signs the token emailed to an invited user. Its jti
+// is the ID of the invitation, which is checked on acceptance so revoked
+// invitations can't be used.
+func GenerateInvitationToken(userID, invitationID string, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		UserID:    userID,
+		TokenType: invitationTokenType,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(7 * 24 * time.Hour)),
+			ID:        invitationID,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -49,17 +143,52 @@ func GenerateRefreshToken(userID string) (string, error) {
 }
 
 func ParseAccessToken(tokenStr string) (*Claims, error) {
-	return parseToken(tokenStr, accessSecret)
+	claims, err := parseToken(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		return signingKeys.publicKey(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
 }
 
 func ParseRefreshToken(tokenStr string) (*Claims, error) {
-	return parseToken(tokenStr, refreshSecret)
+	return parseHMACToken(tokenStr, refreshTokenType)
 }
 
-func parseToken(tokenStr string, secret string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
+func ParseMFAToken(tokenStr string) (*Claims, error) {
+	return parseHMACToken(tokenStr, mfaTokenType)
+}
+
+func ParseInvitationToken(tokenStr string) (*Claims, error) {
+	return parseHMACToken(tokenStr, invitationTokenType)
+}
+
+func parseHMACToken(tokenStr, tokenType string) (*Claims, error) {
+	claims, err := parseToken(tokenStr, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		return []byte(refreshSecret), nil
 	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.TokenType != tokenType || claims.ID == "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+func parseToken(tokenStr string, keyFunc jwt.Keyfunc) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, keyFunc)
 
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid token")
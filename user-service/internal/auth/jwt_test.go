@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"crypto/x509"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func initTestKeys(t *testing.T, kid string) *KeySet {
+	t.Helper()
+	dir := t.TempDir()
+	writeKey(t, dir, kid, false)
+	ks, err := LoadKeySet(dir, "", false)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	Init(ks, "test-secret")
+	return ks
+}
+
+func signHMAC(t *testing.T, claims Claims, secret []byte) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseAccessTokenRejectsHMAC(t *testing.T) {
+	ks := initTestKeys(t, "2025-01-01")
+	_, key := ks.signingKey()
+	publicDER := x509.MarshalPKCS1PublicKey(&key.PublicKey)
+
+	valid := Claims{
+		UserID: "user-1",
+		Role:   "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        "jti",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+	none, err := jwt.NewWithClaims(jwt.SigningMethodNone, valid).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"signed with the refresh secret", signHMAC(t, valid, []byte("test-secret"))},
+		// The classic algorithm confusion: HMAC keyed with the RSA public key
+		{"signed with the public key", signHMAC(t, valid, publicDER)},
+		{"unsigned", none},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAccessToken(tt.token); err == nil {
+				t.Error("ParseAccessToken accepted the token")
+			}
+		})
+	}
+}
+
+func TestParseTokenTypes(t *testing.T) {
+	initTestKeys(t, "2025-01-01")
+	expiresAt := time.Now().Add(time.Hour)
+
+	generate := map[string]func() (string, error){
+		"access": func() (string, error) {
+			return GenerateAccessToken("user-1", "member", "family-1")
+		},
+		"impersonation": func() (string, error) {
+			return GenerateImpersonationToken("user-1", "member", "admin-1", expiresAt)
+		},
+		"refresh": func() (string, error) {
+			return GenerateRefreshToken("user-1", "token-1", expiresAt)
+		},
+		"mfa": func() (string, error) {
+			return GenerateMFAToken("user-1")
+		},
+		"invitation": func() (string, error) {
+			return GenerateInvitationToken("user-1", "invitation-1", expiresAt)
+		},
+	}
+	parse := map[string]func(string) (*Claims, error){
+		"access":     ParseAccessToken,
+		"refresh":    ParseRefreshToken,
+		"mfa":        ParseMFAToken,
+		"invitation": ParseInvitationToken,
+	}
+	// The parser accepting each kind of token; impersonation tokens are
+	// access tokens
+	accepts := map[string]string{
+		"access":        "access",
+		"impersonation": "access",
+		"refresh":       "refresh",
+		"mfa":           "mfa",
+		"invitation":    "invitation",
+	}
+
+	for kind, gen := range generate {
+		token, err := gen()
+		if err != nil {
+			t.Fatalf("generating %s token: %v", kind, err)
+		}
+		for parser, parseToken := range parse {
+			t.Run(kind+" token/"+parser+" parser", func(t *testing.T) {
+				claims, err := parseToken(token)
+				if parser != accepts[kind] {
+					if err == nil {
+						t.Errorf("%s parser accepted a %s token", parser, kind)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("%s parser refused a %s token: %v", parser, kind, err)
+				}
+				if claims.UserID != "user-1" {
+					t.Errorf("UserID = %q, want user-1", claims.UserID)
+				}
+			})
+		}
+	}
+}
+
+func TestParseHMACTokenErrors(t *testing.T) {
+	initTestKeys(t, "2025-01-01")
+	claims := func(typ, id string, expiresAt time.Time) Claims {
+		return Claims{
+			UserID:    "user-1",
+			TokenType: typ,
+			RegisteredClaims: jwt.RegisteredClaims{
+				ID:        id,
+				ExpiresAt: jwt.NewNumericDate(expiresAt),
+			},
+		}
+	}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name   string
+		token  string
+		wantOK bool
+	}{
+		{"valid", signHMAC(t, claims(mfaTokenType, "jti", later), []byte("test-secret")), true},
+		{"other secret", signHMAC(t, claims(mfaTokenType, "jti", later), []byte("other-secret")), false},
+		{"expired", signHMAC(t, claims(mfaTokenType, "jti", time.Now().Add(-time.Minute)), []byte("test-secret")), false},
+		{"without jti", signHMAC(t, claims(mfaTokenType, "", later), []byte("test-secret")), false},
+		{"without type", signHMAC(t, claims("", "jti", later), []byte("test-secret")), false},
+		{"malformed", "not.a.token", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseMFAToken(tt.token); (err == nil) != tt.wantOK {
+				t.Errorf("ParseMFAToken error = %v, want ok %v", err, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseAccessTokenUnknownKey(t *testing.T) {
+	initTestKeys(t, "2025-01-01")
+	token, err := GenerateAccessToken("user-1", "member", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A retired key removed from the set no longer verifies its tokens
+	initTestKeys(t, "2025-06-01")
+	if _, err := ParseAccessToken(token); err == nil {
+		t.Error("ParseAccessToken accepted a token signed with an unknown key")
+	}
+}
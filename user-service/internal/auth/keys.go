@@ -0,0 +1,166 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// KeySet holds the RSA keys used to sign access tokens. Every key in the set
+// is published in the JWKS so tokens signed with a retired key keep verifying
+// until it is removed; only the active key signs new tokens.
+type KeySet struct {
+	activeKID string
+	keys      map[string]*rsa.PrivateKey
+}
+
+// LoadKeySet reads every <kid>.pem RSA private key in dir. activeKID selects
+// the signing key; when empty the last kid in lexical order is used, so naming
+// files by date (2025-01-01.pem) rotates to the newest key. An empty dir is an
+// error unless autoGenerate is set, which writes a fresh key for local
+// development: replicas generating their own keys would publish different
+// JWKS and log everyone out on restart.
+func LoadKeySet(dir, activeKID string, autoGenerate bool) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		if !autoGenerate {
+			return nil, fmt.Errorf("no signing key found in %s", dir)
+		}
+		path, err := generateKeyFile(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		paths = []string{path}
+	}
+
+	ks := &KeySet{keys: make(map[string]*rsa.PrivateKey)}
+	var kids []string
+	for _, path := range paths {
+		key, err := readPrivateKey(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load key %s: %w", path, err)
+		}
+		kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+		ks.keys[kid] = key
+		kids = append(kids, kid)
+	}
+
+	if activeKID == "" {
+		sort.Strings(kids)
+		activeKID = kids[len(kids)-1]
+	}
+	if _, ok := ks.keys[activeKID]; !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", activeKID, dir)
+	}
+	ks.activeKID = activeKID
+
+	return ks, nil
+}
+
+func (ks *KeySet) ActiveKID() string {
+	return ks.activeKID
+}
+
+func (ks *KeySet) signingKey() (string, *rsa.PrivateKey) {
+	return ks.activeKID, ks.keys[ks.activeKID]
+}
+
+func (ks *KeySet) publicKey(kid string) (*rsa.PublicKey, error) {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key")
+	}
+	return &key.PublicKey, nil
+}
+
+// JWK is the public part of a signing key as published in the JWKS.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSHandler serves the public keys at /.well-known/jwks.json.
+func JWKSHandler(ks *KeySet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := make([]JWK, 0, len(ks.keys))
+		for kid, key := range ks.keys {
+			keys = append(keys, JWK{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: "RS256",
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, gin.H{"keys": keys})
+	}
+}
+
+func readPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+func generateKeyFile(dir string) (string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, time.Now().UTC().Format("2006-01-02T150405")+".pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
@@ -0,0 +1,179 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKey writes a new RSA key to dir as <kid>.pem, PKCS#1 or PKCS#8 encoded.
+func writeKey(t *testing.T, dir, kid string, pkcs8 bool) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+	if pkcs8 {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	if err := os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLoadKeySet(t *testing.T) {
+	tests := []struct {
+		name      string
+		kids      []string
+		activeKID string
+		wantKID   string
+	}{
+		{"single key", []string{"2025-01-01"}, "", "2025-01-01"},
+		{"last kid in lexical order", []string{"2025-01-01", "2025-06-01", "2024-12-31"}, "", "2025-06-01"},
+		{"configured kid", []string{"2025-01-01", "2025-06-01"}, "2025-01-01", "2025-01-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for i, kid := range tt.kids {
+				writeKey(t, dir, kid, i%2 == 1)
+			}
+
+			ks, err := LoadKeySet(dir, tt.activeKID, false)
+			if err != nil {
+				t.Fatalf("LoadKeySet: %v", err)
+			}
+			if ks.ActiveKID() != tt.wantKID {
+				t.Errorf("ActiveKID = %q, want %q", ks.ActiveKID(), tt.wantKID)
+			}
+			for _, kid := range tt.kids {
+				if _, err := ks.publicKey(kid); err != nil {
+					t.Errorf("key %q wasn't loaded: %v", kid, err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadKeySetErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		setup     func(t *testing.T, dir string)
+		activeKID string
+	}{
+		{"empty directory", func(t *testing.T, dir string) {}, ""},
+		{"missing directory", func(t *testing.T, dir string) { os.Remove(dir) }, ""},
+		{"unknown configured kid", func(t *testing.T, dir string) { writeKey(t, dir, "2025-01-01", false) }, "2024-01-01"},
+		{"not PEM", func(t *testing.T, dir string) {
+			os.WriteFile(filepath.Join(dir, "bad.pem"), []byte("not a key"), 0o600)
+		}, ""},
+		{"not an RSA key", func(t *testing.T, dir string) {
+			os.WriteFile(filepath.Join(dir, "bad.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: []byte("junk")}), 0o600)
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			tt.setup(t, dir)
+			if _, err := LoadKeySet(dir, tt.activeKID, false); err == nil {
+				t.Error("LoadKeySet succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestLoadKeySetAutoGenerate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	ks, err := LoadKeySet(dir, "", true)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+
+	// The generated key is kept, so the next start uses the same one
+	reloaded, err := LoadKeySet(dir, "", false)
+	if err != nil {
+		t.Fatalf("LoadKeySet after generating: %v", err)
+	}
+	if reloaded.ActiveKID() != ks.ActiveKID() {
+		t.Errorf("ActiveKID = %q after reload, want %q", reloaded.ActiveKID(), ks.ActiveKID())
+	}
+}
+
+func TestJWKSHandler(t *testing.T) {
+	dir := t.TempDir()
+	writeKey(t, dir, "2025-01-01", false)
+	writeKey(t, dir, "2025-06-01", true)
+	ks, err := LoadKeySet(dir, "", false)
+	if err != nil {
+		t.Fatalf("LoadKeySet: %v", err)
+	}
+	Init(ks, "test-secret")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", JWKSHandler(ks))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	var jwks struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &jwks); err != nil {
+		t.Fatalf("invalid JWKS: %v", err)
+	}
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want both", len(jwks.Keys))
+	}
+	published := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || k.Alg != "RS256" || k.Use != "sig" {
+			t.Errorf("key %q = %+v, want an RS256 signing key", k.Kid, k)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			t.Fatalf("invalid n of key %q: %v", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			t.Fatalf("invalid e of key %q: %v", k.Kid, err)
+		}
+		published[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+
+	// Other services verify access tokens with nothing but the JWKS
+	tokenStr, err := GenerateAccessToken("user-1", "member", "")
+	if err != nil {
+		t.Fatalf("GenerateAccessToken: %v", err)
+	}
+	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		return published[kid], nil
+	}, jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil || !token.Valid {
+		t.Fatalf("token doesn't verify with the published keys: %v", err)
+	}
+	if kid := token.Header["kid"]; kid != "2025-06-01" {
+		t.Errorf("token signed with kid %v, want the active 2025-06-01", kid)
+	}
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	loginFailuresEmailKeyPrefix = "auth:login-failures:email:"
+	loginFailuresIPKeyPrefix    = "auth:login-failures:ip:"
+	loginLockKeyPrefix          = "auth:login-lock:"
+)
+
+// Codes of LoginBlockedError, surfaced to clients as the GraphQL error code.
+const (
+	LoginErrorAccountLocked   = "ACCOUNT_LOCKED"
+	LoginErrorTooManyAttempts = "TOO_MANY_ATTEMPTS"
+)
+
+// LoginBlockedError is returned when a login attempt is refused without
+// looking at the password.
+type LoginBlockedError struct {
+	Code       string
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	if e.Code == LoginErrorAccountLocked {
+		return "account is temporarily locked after too many failed logins"
+	}
+	return "too many failed login attempts, try again later"
+}
+
+// LoginLimiterConfig holds the thresholds of a LoginLimiter.
+type LoginLimiterConfig struct {
+	// MaxFailures failed logins for one email within Window lock the account
+	// for LockoutDuration.
+	MaxFailures     int
+	LockoutDuration time.Duration
+	// IPMaxFailures failed logins from one IP within Window block that IP
+	// until the oldest of them leaves the window.
+	IPMaxFailures int
+	Window        time.Duration
+	// Every failure after the first waits BaseDelay, doubled per failure and
+	// capped at MaxDelay, before it is answered.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// LoginLimiter counts failed logins per email and per client IP in Redis
+// sliding windows.
+type LoginLimiter struct {
+	redis *redis.Client
+	cfg   LoginLimiterConfig
+}
+
+func NewLoginLimiter(client *redis.Client, cfg LoginLimiterConfig) *LoginLimiter {
+	return &LoginLimiter{redis: client, cfg: cfg}
+}
+
+// Check returns a *LoginBlockedError if email is locked or ip has too many
+// recent failures.
+func (l *LoginLimiter) Check(ctx context.Context, email, ip string) error {
+	ttl, err := l.redis.PTTL(ctx, loginLockKeyPrefix+normalizeEmail(email)).Result()
+	if err != nil {
+		return err
+	}
+	if ttl > 0 {
+		return &LoginBlockedError{Code: LoginErrorAccountLocked, RetryAfter: ttl}
+	}
+
+	if ip == "" {
+		return nil
+	}
+	key := loginFailuresIPKeyPrefix + ip
+	since := time.Now().Add(-l.cfg.Window)
+	recent, err := l.redis.ZRangeByScoreWithScores(ctx, key, &redis.ZRangeBy{
+		Min: strconv.FormatInt(since.UnixMilli(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return err
+	}
+	if len(recent) >= l.cfg.IPMaxFailures {
+		oldest := time.UnixMilli(int64(recent[0].Score))
+		return &LoginBlockedError{
+			Code:       LoginErrorTooManyAttempts,
+			RetryAfter: time.Until(oldest.Add(l.cfg.Window)),
+		}
+	}
+
+	return nil
+}
+
+// RecordFailure counts a failed login and returns how long to wait before
+// answering it. Once email reaches MaxFailures it is locked and a
+// *LoginBlockedError is returned instead.
+func (l *LoginLimiter) RecordFailure(ctx context.Context, email, ip string) (time.Duration, error) {
+	email = normalizeEmail(email)
+	failures, err := l.addFailure(ctx, loginFailuresEmailKeyPrefix+email)
+	if err != nil {
+		return 0, err
+	}
+	if ip != "" {
+		if _, err := l.addFailure(ctx, loginFailuresIPKeyPrefix+ip); err != nil {
+			return 0, err
+		}
+	}
+
+	if failures >= int64(l.cfg.MaxFailures) {
+		pipe := l.redis.TxPipeline()
+		pipe.Set(ctx, loginLockKeyPrefix+email, "1", l.cfg.LockoutDuration)
+		pipe.Del(ctx, loginFailuresEmailKeyPrefix+email)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return 0, err
+		}
+		return 0, &LoginBlockedError{Code: LoginErrorAccountLocked, RetryAfter: l.cfg.LockoutDuration}
+	}
+
+	return l.delay(failures), nil
+}
+
+// Reset forgets the failed logins of email after a successful login.
+func (l *LoginLimiter) Reset(ctx context.Context, email string) error {
+	return l.redis.Del(ctx, loginFailuresEmailKeyPrefix+normalizeEmail(email)).Err()
+}
+
+// Unlock lifts the lockout of email and forgets its failed logins.
+func (l *LoginLimiter) Unlock(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	return l.redis.Del(ctx, loginLockKeyPrefix+email, loginFailuresEmailKeyPrefix+email).Err()
+}
+
+// addFailure records a failure now in the sorted set at key and returns the
+// number of failures still inside the window.
+func (l *LoginLimiter) addFailure(ctx context.Context, key string) (int64, error) {
+	now := time.Now()
+	pipe := l.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, "-inf", fmt.Sprintf("(%d", now.Add(-l.cfg.Window).UnixMilli()))
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(now.UnixMilli()), Member: uuid.NewString()})
+	count := pipe.ZCard(ctx, key)
+	pipe.Expire(ctx, key, l.cfg.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+	return count.Val(), nil
+}
+
+func (l *LoginLimiter) delay(failures int64) time.Duration {
+	if failures <= 1 {
+		return 0
+	}
+	d := l.cfg.BaseDelay
+	for i := int64(2); i < failures && d < l.cfg.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, l.cfg.MaxDelay)
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
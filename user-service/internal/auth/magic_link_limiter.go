@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const magicLinkRequestsKeyPrefix = "auth:magic-link:email:"
+
+// MagicLinkLimiter bounds how many login links can be requested per email in
+// a fixed window, so the mutation can't be used to flood a mailbox.
+type MagicLinkLimiter struct {
+	redis       *redis.Client
+	maxRequests int
+	window      time.Duration
+}
+
+func NewMagicLinkLimiter(client *redis.Client, maxRequests int, window time.Duration) *MagicLinkLimiter {
+	return &MagicLinkLimiter{redis: client, maxRequests: maxRequests, window: window}
+}
+
+// Allow counts a request for email. Once the limit is reached it returns false
+// and how long until the window ends.
+func (l *MagicLinkLimiter) Allow(ctx context.Context, email string) (bool, time.Duration, error) {
+	key := magicLinkRequestsKeyPrefix + normalizeEmail(email)
+	pipe := l.redis.TxPipeline()
+	count := pipe.Incr(ctx, key)
+	pipe.ExpireNX(ctx, key, l.window)
+	ttl := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return false, 0, err
+	}
+	if count.Val() > int64(l.maxRequests) {
+		return false, ttl.Val(), nil
+	}
+	return true, 0, nil
+}
@@ -1,24 +1,30 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
 // Middleware: nếu có Authorization header → gắn claims vào context
-func AuthMiddleware() gin.HandlerFunc {
+// Bearer credentials are either access tokens (users) or API keys (service
+// accounts).
+func AuthMiddleware(revocations *RevocationStore, apiKeys *APIKeyStore, sessions *SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		ctx := WithClientIP(c.Request.Context(), c.ClientIP())
+		c.Request = c.Request.WithContext(WithUserAgent(ctx, c.Request.UserAgent()))
+
 		authHeader := c.GetHeader("Authorization")
 
 		if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
 			tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-			claims, err := ParseAccessToken(tokenStr)
+			ctx, err := authenticate(c.Request.Context(), revocations, apiKeys, sessions, tokenStr)
 			if err == nil {
 				// Token hợp lệ → gắn vào context
-				ctx := WithUserID(c.Request.Context(), claims.UserID)
-				ctx = WithRole(ctx, claims.Role)
 				c.Request = c.Request.WithContext(ctx)
 			}
 			// Nếu token lỗi → bỏ qua, không chặn ở đây
@@ -27,3 +33,82 @@ func AuthMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// authenticate attaches the caller identified by a bearer credential to ctx.
+func authenticate(ctx context.Context, revocations *RevocationStore, apiKeys *APIKeyStore, sessions *SessionStore, credential string) (context.Context, error) {
+	if IsAPIKey(credential) {
+		principal, err := apiKeys.Authenticate(ctx, credential)
+		if err != nil {
+			return ctx, err
+		}
+		return WithServicePrincipal(ctx, principal), nil
+	}
+
+	claims, err := VerifyAccessToken(ctx, revocations, credential)
+	if err != nil {
+		return ctx, err
+	}
+	if claims.FamilyID != "" {
+		sessions.Touch(ctx, claims.FamilyID)
+	}
+	return withAuthenticatedUser(ctx, claims), nil
+}
+
+// withAuthenticatedUser attaches the caller identified by claims to ctx.
+func withAuthenticatedUser(ctx context.Context, claims *Claims) context.Context {
+	ctx = WithUserID(ctx, claims.UserID)
+	ctx = WithRole(ctx, claims.Role)
+	return WithClaims(ctx, claims)
+}
+
+// VerifyAccessToken parses an access token and rejects it if it was revoked.
+// A failing revocation lookup is treated as revoked so outages fail closed.
+func VerifyAccessToken(ctx context.Context, revocations *RevocationStore, tokenStr string) (*Claims, error) {
+	claims, err := ParseAccessToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+	if err := CheckAccessToken(ctx, revocations, claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CheckAccessToken rejects the claims of an already verified access token if
+// it, its session or its user were revoked since. Long-lived connections such
+// as subscriptions call it again before every message they deliver.
+func CheckAccessToken(ctx context.Context, revocations *RevocationStore, claims *Claims) error {
+	if claims.ID != "" {
+		revoked, err := revocations.IsRevoked(ctx, claims.ID)
+		if err != nil || revoked {
+			return errors.New("token revoked")
+		}
+	}
+
+	if claims.FamilyID != "" {
+		revoked, err := revocations.IsSessionRevoked(ctx, claims.FamilyID)
+		if err != nil || revoked {
+			return errors.New("session revoked")
+		}
+	}
+
+	// Tokens without iat predate per-user revocation and count as oldest
+	var issuedAt time.Time
+	if claims.IssuedAt != nil {
+		issuedAt = claims.IssuedAt.Time
+	}
+	revoked, err := revocations.IsUserRevoked(ctx, claims.UserID, issuedAt)
+	if err != nil || revoked {
+		return errors.New("token revoked")
+	}
+
+	// Impersonation ends as soon as the admin loses their access, too
+	if claims.Actor != nil {
+		revoked, err := revocations.IsUserRevoked(ctx, claims.Actor.UserID, issuedAt)
+		if err != nil || revoked {
+			return errors.New("token revoked")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The key prefixes are shared with the team-service and asset-service
+// middlewares, which read the same keys to reject revoked tokens.
+const (
+	revokedTokenKeyPrefix   = "auth:revoked:"
+	revokedUserKeyPrefix    = "auth:revoked-user:"
+	revokedSessionKeyPrefix = "auth:revoked-session:"
+)
+
+// RevocationStore records the jti of access tokens that were invalidated
+// before their expiry, the sessions (token families) that were ended, and
+// users whose earlier tokens were all invalidated.
+// Entries expire together with the tokens they revoke.
+type RevocationStore struct {
+	redis *redis.Client
+}
+
+func NewRevocationStore(client *redis.Client) *RevocationStore {
+	return &RevocationStore{redis: client}
+}
+
+// Revoke marks tokenID as revoked until expiresAt.
+func (s *RevocationStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.redis.Set(ctx, revokedTokenKeyPrefix+tokenID, "1", ttl).Err()
+}
+
+func (s *RevocationStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	n, err := s.redis.Exists(ctx, revokedTokenKeyPrefix+tokenID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeUser invalidates every access token issued to userID until now.
+func (s *RevocationStore) RevokeUser(ctx context.Context, userID string) error {
+	return s.redis.Set(ctx, revokedUserKeyPrefix+userID, time.Now().Unix(), AccessTokenTTL).Err()
+}
+
+// IsUserRevoked reports whether a token issued to userID at issuedAt was
+// invalidated by RevokeUser.
+func (s *RevocationStore) IsUserRevoked(ctx context.Context, userID string, issuedAt time.Time) (bool, error) {
+	revokedAt, err := s.redis.Get(ctx, revokedUserKeyPrefix+userID).Int64()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return issuedAt.Unix() < revokedAt, nil
+}
+
+// RevokeSession invalidates every access token of the session sessionID, the
+// fid claim of the tokens.
+func (s *RevocationStore) RevokeSession(ctx context.Context, sessionID string) error {
+	return s.redis.Set(ctx, revokedSessionKeyPrefix+sessionID, "1", AccessTokenTTL).Err()
+}
+
+func (s *RevocationStore) IsSessionRevoked(ctx context.Context, sessionID string) (bool, error) {
+	n, err := s.redis.Exists(ctx, revokedSessionKeyPrefix+sessionID).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
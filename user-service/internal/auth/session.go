@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"user-service/internal/model"
+
+	"github.com/redis/go-redis/v9"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionSeenKeyPrefix = "auth:session-seen:"
+	// sessionLastSeenPrecision limits last_seen_at writes for busy sessions.
+	sessionLastSeenPrecision = time.Minute
+)
+
+// SessionStore keeps the last activity of sessions up to date as their
+// access tokens are used.
+type SessionStore struct {
+	db    *gorm.DB
+	redis *redis.Client
+}
+
+func NewSessionStore(db *gorm.DB, client *redis.Client) *SessionStore {
+	return &SessionStore{db: db, redis: client}
+}
+
+// Touch records activity on sessionID. Writes are throttled through Redis so
+// only the first request of each minute reaches the database.
+func (s *SessionStore) Touch(ctx context.Context, sessionID string) {
+	first, err := s.redis.SetNX(ctx, sessionSeenKeyPrefix+sessionID, "1", sessionLastSeenPrecision).Result()
+	if err != nil || !first {
+		return
+	}
+
+	err = s.db.WithContext(ctx).Model(&model.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("last_seen_at", time.Now()).Error
+	if err != nil {
+		slog.Warn("Failed to update session activity", "sessionId", sessionID, "error", err)
+	}
+}
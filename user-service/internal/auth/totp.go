@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30 * time.Second
+	// totpSkew is how many periods before and after the current one are
+	// still accepted, to absorb clock drift.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTP implements RFC 6238 time-based one-time passwords (SHA-1, 6 digits,
+// 30s period, as expected by authenticator apps). Secrets are sealed with
+// AES-GCM before they are stored.
+type TOTP struct {
+	issuer string
+	aead   cipher.AEAD
+}
+
+// NewTOTP returns a TOTP whose otpauth URIs name issuer and whose secrets are
+// sealed with a key derived from encryptionKey.
+func NewTOTP(issuer, encryptionKey string) (*TOTP, error) {
+	key := sha256.Sum256([]byte(encryptionKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTP{issuer: issuer, aead: aead}, nil
+}
+
+// NewSecret generates a secret and returns it base32 encoded, for the user,
+// and sealed, for storage.
+func (t *TOTP) NewSecret() (secret string, sealed string, err error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	secret = totpEncoding.EncodeToString(b)
+
+	nonce := make([]byte, t.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", "", err
+	}
+	sealed = base64.StdEncoding.EncodeToString(t.aead.Seal(nonce, nonce, []byte(secret), nil))
+	return secret, sealed, nil
+}
+
+// URI returns the otpauth:// URI authenticator apps enroll from.
+func (t *TOTP) URI(account, secret string) string {
+	label := url.PathEscape(t.issuer + ":" + account)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", t.issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(totpDigits))
+	q.Set("period", fmt.Sprint(int(totpPeriod.Seconds())))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+// Validate checks code against the sealed secret at now. It returns the time
+// step the code belongs to so callers can refuse replaying it.
+func (t *TOTP) Validate(sealed, code string, now time.Time) (step int64, ok bool, err error) {
+	secret, err := t.open(sealed)
+	if err != nil {
+		return 0, false, err
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		return 0, false, err
+	}
+
+	code = strings.TrimSpace(code)
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for s := current - totpSkew; s <= current+totpSkew; s++ {
+		if subtle.ConstantTimeCompare([]byte(totpCode(key, s)), []byte(code)) == 1 {
+			return s, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (t *TOTP) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	n := t.aead.NonceSize()
+	if len(data) < n {
+		return "", errors.New("invalid sealed secret")
+	}
+	secret, err := t.aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func totpCode(key []byte, step int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// NewRecoveryCodes returns single-use codes that stand in for a TOTP code
+// when the authenticator is lost. Store them with HashOpaqueToken.
+func NewRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes, nil
+}
+
+// NormalizeRecoveryCode lets users type recovery codes without caring about
+// case or the dash.
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != 8 {
+		return code
+	}
+	return code[:4] + "-" + code[4:]
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// The shared secret of the RFC 6238 test vectors, "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func sealSecret(t *testing.T, totp *TOTP, secret string) string {
+	t.Helper()
+	nonce := make([]byte, totp.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(totp.aead.Seal(nonce, nonce, []byte(secret), nil))
+}
+
+func TestTOTPCode(t *testing.T) {
+	key, err := totpEncoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// RFC 6238 appendix B, SHA-1, truncated to 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		step := tt.unix / int64(totpPeriod.Seconds())
+		if got := totpCode(key, step); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestTOTPValidateWindow(t *testing.T) {
+	totp, err := NewTOTP("Test", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := sealSecret(t, totp, rfcSecret)
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / int64(totpPeriod.Seconds())
+
+	tests := []struct {
+		name     string
+		code     string
+		wantOK   bool
+		wantStep int64
+	}{
+		{"current step", totpCode(key, current), true, current},
+		{"previous step", totpCode(key, current-1), true, current - 1},
+		{"next step", totpCode(key, current+1), true, current + 1},
+		{"two steps ago", totpCode(key, current-2), false, 0},
+		{"two steps ahead", totpCode(key, current+2), false, 0},
+		{"surrounding whitespace", " " + totpCode(key, current) + "\n", true, current},
+		{"wrong code", "000000", false, 0},
+		{"empty", "", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok, err := totp.Validate(sealed, tt.code, now)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if ok != tt.wantOK || step != tt.wantStep {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.wantStep, tt.wantOK)
+			}
+		})
+	}
+}
+
+// Replays are refused by callers recording the last accepted step, which only
+// works if a code always maps to the step it was generated for.
+func TestTOTPValidateReplay(t *testing.T) {
+	totp, err := NewTOTP("Test", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed := sealSecret(t, totp, rfcSecret)
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	code := totpCode(key, current)
+
+	tests := []struct {
+		name string
+		at   time.Time
+	}{
+		{"same time", now},
+		{"next period", now.Add(totpPeriod)},
+		{"previous period", now.Add(-totpPeriod)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok, err := totp.Validate(sealed, code, tt.at)
+			if err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			if !ok {
+				t.Fatal("code within the window was refused")
+			}
+			if step != current {
+				t.Errorf("step = %d, want %d", step, current)
+			}
+		})
+	}
+
+	// A replay once the window moved on doesn't validate at all
+	if _, ok, _ := totp.Validate(sealed, code, now.Add(2*totpPeriod)); ok {
+		t.Error("code was accepted two periods later")
+	}
+}
+
+func TestTOTPValidateSealedSecret(t *testing.T) {
+	totp, err := NewTOTP("Test", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewTOTP("Test", "other-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		sealed string
+	}{
+		{"other encryption key", sealSecret(t, other, rfcSecret)},
+		{"not base64", "!!"},
+		{"shorter than a nonce", base64.StdEncoding.EncodeToString([]byte("short"))},
+		{"not a base32 secret", sealSecret(t, totp, "not base32!")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok, err := totp.Validate(tt.sealed, "123456", time.Now()); err == nil || ok {
+				t.Errorf("Validate = %v, %v, want an error", ok, err)
+			}
+		})
+	}
+}
+
+func TestTOTPNewSecretRoundTrip(t *testing.T) {
+	totp, err := NewTOTP("Test", "test-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, sealed, err := totp.NewSecret()
+	if err != nil {
+		t.Fatalf("NewSecret: %v", err)
+	}
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret isn't base32: %v", err)
+	}
+
+	now := time.Now()
+	code := totpCode(key, now.Unix()/int64(totpPeriod.Seconds()))
+	if _, ok, err := totp.Validate(sealed, code, now); err != nil || !ok {
+		t.Errorf("Validate = %v, %v, want the code of the new secret to be accepted", ok, err)
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"abcd-efgh", "abcd-efgh"},
+		{"ABCD-EFGH", "abcd-efgh"},
+		{"abcdefgh", "abcd-efgh"},
+		{" abcd-efgh\n", "abcd-efgh"},
+		{"ab-cd-ef-gh", "abcd-efgh"},
+		{"abc", "abc"},
+	}
+	for _, tt := range tests {
+		if got := NormalizeRecoveryCode(tt.code); got != tt.want {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", tt.code, got, tt.want)
+		}
+	}
+}
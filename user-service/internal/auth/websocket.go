@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+)
+
+// WebsocketInit authenticates GraphQL websocket connections from the
+// Authorization entry of their init payload, the same way AuthMiddleware does
+// for HTTP requests. Connections without one stay anonymous; a bad credential
+// refuses the connection.
+func WebsocketInit(revocations *RevocationStore, apiKeys *APIKeyStore, sessions *SessionStore) transport.WebsocketInitFunc {
+	return func(ctx context.Context, initPayload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
+		authorization := initPayload.Authorization()
+		if authorization == "" {
+			return ctx, nil, nil
+		}
+
+		credential := strings.TrimPrefix(authorization, "Bearer ")
+		ctx, err := authenticate(ctx, revocations, apiKeys, sessions, credential)
+		if err != nil {
+			return ctx, nil, errors.New("invalid token")
+		}
+
+		return ctx, nil, nil
+	}
+}
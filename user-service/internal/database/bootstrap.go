@@ -0,0 +1,55 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"user-service/config"
+	"user-service/internal/model"
+	"user-service/internal/password"
+
+	"gorm.io/gorm"
+)
+
+// BootstrapAdmin makes sure there is an admin to create everyone else. While
+// no admin exists, the user with cfg.Email is promoted, or created with
+// cfg.Username and cfg.Password. It reports whether anything changed.
+func BootstrapAdmin(db *gorm.DB, cfg config.AdminConfig, passwords *password.Policy) (bool, error) {
+	if cfg.Email == "" {
+		return false, nil
+	}
+
+	var admins int64
+	if err := db.Model(&model.User{}).Where("role = ?", model.RoleAdmin).Count(&admins).Error; err != nil {
+		return false, err
+	}
+	if admins > 0 {
+		return false, nil
+	}
+
+	var user model.User
+	err := db.Where("email = ?", cfg.Email).First(&user).Error
+	if err == nil {
+		return true, db.Model(&user).Update("role", model.RoleAdmin).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+
+	if err := passwords.Validate(cfg.Password); err != nil {
+		return false, fmt.Errorf("bootstrap admin password: %w", err)
+	}
+	hash, err := passwords.Hash(cfg.Password)
+	if err != nil {
+		return false, err
+	}
+
+	// The address comes from the operator, not from a user
+	return true, db.Create(&model.User{
+		Username:      cfg.Username,
+		Email:         cfg.Email,
+		EmailVerified: true,
+		Role:          model.RoleAdmin,
+		Status:        model.UserStatusActive,
+		PasswordHash:  hash,
+	}).Error
+}
@@ -0,0 +1,9 @@
+package database
+
+import "strings"
+
+// EscapeLike escapes the LIKE wildcards in a user-supplied search term, for
+// patterns used with ESCAPE '\' (the Postgres default).
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -14,5 +14,18 @@ func Migrate(db *gorm.DB) error {
     // Correct order: parent tables before child tables
     return db.AutoMigrate(
         &model.User{},
+        &model.RefreshToken{},
+        &model.Session{},
+        &model.AuthEvent{},
+        &model.PasswordResetToken{},
+        &model.EmailVerificationToken{},
+        &model.MagicLinkToken{},
+        &model.Invitation{},
+        &model.PasswordHistory{},
+        &model.RecoveryCode{},
+        &model.UserPreference{},
+        &model.RoleSetting{},
+        &model.ServiceAccount{},
+        &model.APIKey{},
     )
 }
\ No newline at end of file
@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"user-service/internal/auth"
+	"user-service/internal/messaging"
+	"user-service/internal/model"
+	"user-service/internal/pubsub"
+)
+
+// Event types published to the user.events topic
+const (
+	UserCreated     = "USER_CREATED"
+	UserUpdated     = "USER_UPDATED"
+	UserRoleChanged = "USER_ROLE_CHANGED"
+	UserDeactivated = "USER_DEACTIVATED"
+	UserReactivated = "USER_REACTIVATED"
+	UserDeleted     = "USER_DELETED"
+)
+
+// UserEvents publishes user lifecycle events, wherever the change comes from
+// (GraphQL mutations or SCIM provisioning).
+type UserEvents struct {
+	kafka   *messaging.KafkaProducer
+	changes *pubsub.UserChanges
+}
+
+func NewUserEvents(kafka *messaging.KafkaProducer, changes *pubsub.UserChanges) *UserEvents {
+	return &UserEvents{kafka: kafka, changes: changes}
+}
+
+// Publish emits a lifecycle event keyed by user ID, so all events of one user
+// land on the same partition in order, and notifies userChanged subscribers.
+// When an admin made the change while impersonating performedBy, they are
+// recorded as actedBy. Like the other services, publishing is best effort and
+// never fails the change itself.
+func (e *UserEvents) Publish(ctx context.Context, eventType string, user *model.User, performedBy string) {
+	actedBy, _ := auth.GetActorFromContext(ctx)
+	event := map[string]interface{}{
+		"eventType":   eventType,
+		"userId":      user.UserID,
+		"username":    user.Username,
+		"email":       user.Email,
+		"role":        user.Role,
+		"status":      user.Status,
+		"performedBy": performedBy,
+		"timestamp":   time.Now().UTC().Format(time.RFC3339),
+	}
+	if actedBy != "" {
+		event["actedBy"] = actedBy
+	}
+	_ = e.kafka.Publish(ctx, user.UserID, event)
+
+	_ = e.changes.Publish(ctx, &pubsub.UserChange{
+		Type:        eventType,
+		User:        *user,
+		PerformedBy: performedBy,
+		ActedBy:     actedBy,
+		OccurredAt:  time.Now().UTC(),
+	})
+}
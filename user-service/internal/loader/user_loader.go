@@ -0,0 +1,146 @@
+package loader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"user-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type contextKey string
+
+const userLoaderKey = contextKey("userLoader")
+
+// batchWait is how long a batch stays open for more keys before it is fetched.
+const batchWait = 2 * time.Millisecond
+
+// UserLoader batches user lookups made while resolving one request into a
+// single query and caches the results for the rest of that request.
+type UserLoader struct {
+	db *gorm.DB
+
+	mu    sync.Mutex
+	cache map[string]*model.User
+	batch *userBatch
+}
+
+type userBatch struct {
+	keys []string
+	done chan struct{}
+	err  error
+}
+
+func NewUserLoader(db *gorm.DB) *UserLoader {
+	return &UserLoader{
+		db:    db,
+		cache: make(map[string]*model.User),
+	}
+}
+
+// Middleware attaches a fresh UserLoader to every request.
+func Middleware(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := context.WithValue(c.Request.Context(), userLoaderKey, NewUserLoader(db))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// For returns the request's UserLoader.
+func For(ctx context.Context) *UserLoader {
+	return ctx.Value(userLoaderKey).(*UserLoader)
+}
+
+// Load returns the user with the given ID, or nil if it does not exist.
+func (l *UserLoader) Load(ctx context.Context, userID string) (*model.User, error) {
+	users, err := l.LoadAll(ctx, []string{userID})
+	if err != nil {
+		return nil, err
+	}
+	return users[0], nil
+}
+
+// LoadAll returns users in the order of userIDs, with nil for unknown IDs.
+func (l *UserLoader) LoadAll(ctx context.Context, userIDs []string) ([]*model.User, error) {
+	l.mu.Lock()
+	var missing []string
+	for _, id := range userIDs {
+		if _, ok := l.cache[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	var batch *userBatch
+	if len(missing) > 0 {
+		batch = l.batch
+		if batch == nil {
+			batch = &userBatch{done: make(chan struct{})}
+			l.batch = batch
+			go l.fetchAfterWait(batch)
+		}
+		batch.keys = append(batch.keys, missing...)
+	}
+	l.mu.Unlock()
+
+	if batch != nil {
+		select {
+		case <-batch.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if batch.err != nil {
+			return nil, batch.err
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	users := make([]*model.User, len(userIDs))
+	for i, id := range userIDs {
+		users[i] = l.cache[id]
+	}
+	return users, nil
+}
+
+func (l *UserLoader) fetchAfterWait(batch *userBatch) {
+	time.Sleep(batchWait)
+
+	// Close the batch so later loads start a new one
+	l.mu.Lock()
+	if l.batch == batch {
+		l.batch = nil
+	}
+	keys := batch.keys
+	l.mu.Unlock()
+
+	// Malformed IDs can't match any row and would make Postgres reject the query
+	var validKeys []string
+	for _, id := range keys {
+		if _, err := uuid.Parse(id); err == nil {
+			validKeys = append(validKeys, id)
+		}
+	}
+
+	var users []*model.User
+	if len(validKeys) > 0 {
+		batch.err = l.db.Where("user_id IN ?", validKeys).Find(&users).Error
+	}
+
+	l.mu.Lock()
+	if batch.err == nil {
+		for _, id := range keys {
+			l.cache[id] = nil
+		}
+		for _, u := range users {
+			l.cache[u.UserID] = u
+		}
+	}
+	l.mu.Unlock()
+
+	close(batch.done)
+}
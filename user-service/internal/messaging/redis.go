@@ -0,0 +1,11 @@
+package messaging
+
+import (
+	"github.com/redis/go-redis/v9"
+)
+
+func NewRedisClient(addr string) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr: addr,
+	})
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// AuthEvent is an entry of the authentication audit trail. UserID is nil for
+// failed logins with an email that has no account.
+type AuthEvent struct {
+	ID     string  `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID *string `gorm:"type:uuid;index:idx_auth_events_user_created"`
+	// ActorID is the admin who impersonated UserID, for events of
+	// impersonation tokens and for starting an impersonation
+	ActorID *string `gorm:"type:uuid;index"`
+	Email   string  `gorm:"size:100"`
+	Type    string  `gorm:"size:30;not null"`
+	// Detail is how a login was made, why it failed, or why an admin
+	// impersonated the user
+	Detail    string `gorm:"size:500"`
+	IPAddress string `gorm:"size:45"`
+	UserAgent string
+	CreatedAt time.Time `gorm:"autoCreateTime;index;index:idx_auth_events_user_created"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// EmailVerificationToken is a single-use link proving that its recipient owns
+// Email. Only the SHA-256 of the emailed token is stored.
+type EmailVerificationToken struct {
+	ID        string    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	Email     string    `gorm:"size:100;not null"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// Invitation is an emailed invitation to activate the pending user UserID.
+// The signed token carries the invitation ID, so an invitation that was
+// accepted, revoked or replaced by a newer one can't be used anymore.
+type Invitation struct {
+	ID         string    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID     string    `gorm:"type:uuid;not null;index"`
+	Email      string    `gorm:"size:100;not null"`
+	Role       string    `gorm:"size:20;not null"`
+	InvitedBy  string    `gorm:"type:uuid;not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	AcceptedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// MagicLinkToken is a single-use login link. Only the SHA-256 of the emailed
+// token is stored.
+type MagicLinkToken struct {
+	ID        string    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    string    `gorm:"type:uuid;not null;index"`
+	TokenHash string    `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
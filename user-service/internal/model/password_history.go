@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// PasswordHistory keeps the hashes of a user's previous passwords so the
+// password policy can refuse reusing them.
+type PasswordHistory struct {
+	ID           string    `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID       string    `gorm:"type:uuid;not null;index"`
+	PasswordHash string    `gorm:"not null"`
+	CreatedAt    time.Time `gorm:"autoCreateTime;index"`
+}
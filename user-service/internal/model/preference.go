@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// UserPreference is a client setting of a user, such as a theme or the
+// columns of a table, stored server side so it follows the user across
+// devices. Type is one of the GraphQL PreferenceType values and Value holds
+// the value encoded as JSON.
+type UserPreference struct {
+	UserID    string    `gorm:"type:uuid;primaryKey"`
+	Key       string    `gorm:"size:64;primaryKey"`
+	Type      string    `gorm:"size:10;not null"`
+	Value     string    `gorm:"type:text;not null"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// RecoveryCode is a single-use code that replaces a TOTP code. Only the
+// SHA-256 of the code is stored.
+type RecoveryCode struct {
+	ID        string `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserID    string `gorm:"type:uuid;not null;index"`
+	CodeHash  string `gorm:"size:64;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// RefreshToken is a single issued refresh token. Tokens obtained from the same
+// login share a FamilyID; rotating a token revokes it and records its successor
+// in ReplacedBy, so presenting an already-rotated token can be detected.
+type RefreshToken struct {
+	TokenID    string    `gorm:"type:uuid;primaryKey"`
+	FamilyID   string    `gorm:"type:uuid;not null;index"`
+	UserID     string    `gorm:"type:uuid;not null;index"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	ReplacedBy *string   `gorm:"type:uuid"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RoleSetting holds the login options admins switch per role. Roles without a
+// row use the defaults, every option off.
+type RoleSetting struct {
+	Role string `gorm:"size:20;primaryKey"`
+	// MagicLinkEnabled lets users of the role log in with an emailed link
+	// instead of their password.
+	MagicLinkEnabled bool      `gorm:"not null;default:false"`
+	UpdatedBy        string    `gorm:"type:uuid"`
+	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ServiceAccount is a non-human principal, such as another service or a
+// background job, that authenticates with API keys instead of a password.
+type ServiceAccount struct {
+	ID   string `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Name string `gorm:"size:100;uniqueIndex;not null"`
+	// Scopes are space separated, like OAuth scopes.
+	Scopes     string `gorm:"not null"`
+	CreatedBy  string `gorm:"type:uuid"`
+	DisabledAt *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime"`
+}
+
+// APIKey is a credential of a ServiceAccount. Only the SHA-256 of the key is
+// stored; Prefix is kept in clear so admins can tell keys apart.
+type APIKey struct {
+	ID               string     `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	ServiceAccountID string     `gorm:"type:uuid;not null;index"`
+	Prefix           string     `gorm:"size:16;not null"`
+	KeyHash          string     `gorm:"size:64;uniqueIndex;not null"`
+	ExpiresAt        *time.Time // set when the key is rotated out
+	RevokedAt        *time.Time
+	LastUsedAt       *time.Time
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+}
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// Session is a device a user is logged in on. Its ID is the FamilyID of the
+// refresh tokens issued for that login, and the fid claim of its access tokens.
+type Session struct {
+	ID         string `gorm:"type:uuid;primaryKey"`
+	UserID     string `gorm:"type:uuid;not null;index"`
+	UserAgent  string
+	IPAddress  string    `gorm:"size:45"`
+	LastSeenAt time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+}
@@ -2,12 +2,42 @@ package model
 
 import "time"
 
+const (
+    UserStatusActive      = "active"
+    UserStatusDeactivated = "deactivated"
+    // UserStatusPending is an invited user who hasn't accepted yet.
+    UserStatusPending     = "pending"
+)
+
 type User struct {
     UserID       string `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+    // ExternalID is the identifier of the user in the identity provider that
+    // provisions it through SCIM.
+    ExternalID   *string `gorm:"size:255;uniqueIndex"`
     Username     string `gorm:"size:50;not null"`
     Email        string `gorm:"size:100;unique;not null"`
+    // EmailVerified is set once the owner of Email followed a link sent to it,
+    // and cleared when Email changes.
+    EmailVerified bool `gorm:"not null;default:false"`
     Role         string `gorm:"size:20;not null"`
-    PasswordHash string `gorm:"not null"`
+    Status       string `gorm:"size:20;not null;default:active"`
+    PasswordHash string `gorm:"not null" json:"-"`
+    // TOTPSecret is sealed by auth.TOTP; it is set once enrollment starts and
+    // only used for login once TOTPEnabled.
+    TOTPSecret   string `gorm:"column:totp_secret" json:"-"`
+    TOTPEnabled  bool   `gorm:"column:totp_enabled;not null;default:false"`
+    // TOTPLastStep is the time step of the last accepted code, so a code
+    // can't be replayed.
+    TOTPLastStep int64  `gorm:"column:totp_last_step;not null;default:0" json:"-"`
+    // Profile, all optional
+    DisplayName  *string `gorm:"size:100"`
+    AvatarURL    *string `gorm:"size:500"`
+    Timezone     *string `gorm:"size:64"`
+    Locale       *string `gorm:"size:35"`
+    JobTitle     *string `gorm:"size:100"`
     CreatedAt    time.Time `gorm:"autoCreateTime"`
     UpdatedAt    time.Time `gorm:"autoUpdateTime"`
 }
+
+// IsEntity marks User as a federation entity (fedruntime.Entity).
+func (User) IsEntity() {}
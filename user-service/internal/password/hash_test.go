@@ -0,0 +1,111 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func testHasher() *Hasher {
+	return &Hasher{Memory: 64, Iterations: 1, Parallelism: 1}
+}
+
+func TestHasherVerify(t *testing.T) {
+	h := testHasher()
+	argon2Hash, err := h.Hash("s3cret-pass")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	// Hashes keep their own parameters, so changing the hasher's doesn't
+	// invalidate them
+	otherHash, err := (&Hasher{Memory: 128, Iterations: 2, Parallelism: 2}).Hash("s3cret-pass")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+	parts := strings.Split(argon2Hash, "$")
+
+	tests := []struct {
+		name     string
+		password string
+		encoded  string
+		want     bool
+	}{
+		{"argon2id", "s3cret-pass", argon2Hash, true},
+		{"argon2id wrong password", "s3cret-pasS", argon2Hash, false},
+		{"argon2id other parameters", "s3cret-pass", otherHash, true},
+		{"bcrypt", "s3cret-pass", string(bcryptHash), true},
+		{"bcrypt wrong password", "wrong", string(bcryptHash), false},
+		{"empty hash", "s3cret-pass", "", false},
+		{"garbage", "s3cret-pass", "not-a-hash", false},
+		{"truncated argon2id", "s3cret-pass", strings.Join(parts[:5], "$"), false},
+		{"unsupported version", "s3cret-pass", strings.Replace(argon2Hash, "v=19", "v=16", 1), false},
+		{"invalid parameters", "s3cret-pass", strings.Replace(argon2Hash, "m=64", "m=x", 1), false},
+		{"invalid salt", "s3cret-pass", strings.Join([]string{"", parts[1], parts[2], parts[3], "!!", parts[5]}, "$"), false},
+		{"empty key", "s3cret-pass", strings.Join([]string{"", parts[1], parts[2], parts[3], parts[4], ""}, "$"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.Verify(tt.password, tt.encoded); got != tt.want {
+				t.Errorf("Verify(%q, %q) = %v, want %v", tt.password, tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHasherHashIsSalted(t *testing.T) {
+	h := testHasher()
+	first, err := h.Hash("s3cret-pass")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	second, err := h.Hash("s3cret-pass")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if first == second {
+		t.Error("hashing the same password twice gave the same hash")
+	}
+	if !strings.HasPrefix(first, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Hash = %q, want the argon2id PHC format with the hasher's parameters", first)
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	h := testHasher()
+	hash := func(h *Hasher) string {
+		encoded, err := h.Hash("s3cret-pass")
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		return encoded
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("GenerateFromPassword: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+		want    bool
+	}{
+		{"current parameters", hash(h), false},
+		{"other memory", hash(&Hasher{Memory: 128, Iterations: 1, Parallelism: 1}), true},
+		{"other iterations", hash(&Hasher{Memory: 64, Iterations: 2, Parallelism: 1}), true},
+		{"other parallelism", hash(&Hasher{Memory: 64, Iterations: 1, Parallelism: 2}), true},
+		{"bcrypt", string(bcryptHash), true},
+		{"malformed", "not-a-hash", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := h.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("NeedsRehash(%q) = %v, want %v", tt.encoded, got, tt.want)
+			}
+		})
+	}
+}
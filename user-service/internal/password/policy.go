@@ -0,0 +1,142 @@
+package password
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"user-service/config"
+)
+
+//go:embed common_passwords.txt
+var commonPasswords string
+
+var (
+	ErrTooShort      = errors.New("password is too short")
+	ErrTooCommon     = errors.New("password is too common")
+	ErrReused        = errors.New("password was used recently")
+	ErrMissingUpper  = errors.New("password must contain an uppercase letter")
+	ErrMissingLower  = errors.New("password must contain a lowercase letter")
+	ErrMissingDigit  = errors.New("password must contain a digit")
+	ErrMissingSymbol = errors.New("password must contain a symbol")
+)
+
+// Policy decides which passwords users may choose, and how they are hashed.
+type Policy struct {
+	*Hasher
+
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// HistorySize is how many previous passwords, the current one included,
+	// can't be chosen again.
+	HistorySize int
+
+	denied map[string]struct{}
+}
+
+// NewPolicy builds a Policy from cfg. The built-in list of common passwords
+// is always denied; cfg.DenyListFile adds one password per line on top.
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	p := &Policy{
+		MinLength:     cfg.MinLength,
+		RequireUpper:  cfg.RequireUpper,
+		RequireLower:  cfg.RequireLower,
+		RequireDigit:  cfg.RequireDigit,
+		RequireSymbol: cfg.RequireSymbol,
+		HistorySize:   cfg.HistorySize,
+		Hasher: &Hasher{
+			Memory:      uint32(cfg.Argon2Memory),
+			Iterations:  uint32(cfg.Argon2Iterations),
+			Parallelism: uint8(cfg.Argon2Parallelism),
+		},
+		denied: make(map[string]struct{}),
+	}
+	if cfg.Argon2Memory <= 0 || cfg.Argon2Iterations <= 0 || cfg.Argon2Parallelism <= 0 || cfg.Argon2Parallelism > 255 {
+		return nil, errors.New("invalid argon2 parameters")
+	}
+
+	_ = p.addDenied(strings.NewReader(commonPasswords))
+	if cfg.DenyListFile != "" {
+		f, err := os.Open(cfg.DenyListFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open password deny-list: %w", err)
+		}
+		defer f.Close()
+		if err := p.addDenied(f); err != nil {
+			return nil, fmt.Errorf("failed to read password deny-list: %w", err)
+		}
+	}
+
+	return p, nil
+}
+
+func (p *Policy) addDenied(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.denied[strings.ToLower(line)] = struct{}{}
+		}
+	}
+	return scanner.Err()
+}
+
+// Validate checks password against the length, character class and deny-list
+// rules. It returns the first rule that isn't met.
+func (p *Policy) Validate(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return fmt.Errorf("%w: at least %d characters required", ErrTooShort, p.MinLength)
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			hasUpper = true
+		case unicode.IsLower(c):
+			hasLower = true
+		case unicode.IsDigit(c):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+	switch {
+	case p.RequireUpper && !hasUpper:
+		return ErrMissingUpper
+	case p.RequireLower && !hasLower:
+		return ErrMissingLower
+	case p.RequireDigit && !hasDigit:
+		return ErrMissingDigit
+	case p.RequireSymbol && !hasSymbol:
+		return ErrMissingSymbol
+	}
+
+	if _, ok := p.denied[strings.ToLower(password)]; ok {
+		return ErrTooCommon
+	}
+
+	return nil
+}
+
+// CheckReuse returns ErrReused if password matches one of the hashes of
+// previous passwords, newest first. Only the first HistorySize are checked.
+func (p *Policy) CheckReuse(password string, previousHashes []string) error {
+	if len(previousHashes) > p.HistorySize {
+		previousHashes = previousHashes[:p.HistorySize]
+	}
+	for _, hash := range previousHashes {
+		if p.Verify(password, hash) {
+			return ErrReused
+		}
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"user-service/config"
+)
+
+func testPolicyConfig() config.PasswordConfig {
+	return config.PasswordConfig{
+		MinLength:         10,
+		RequireUpper:      true,
+		RequireLower:      true,
+		RequireDigit:      true,
+		RequireSymbol:     true,
+		HistorySize:       2,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+}
+
+func TestPolicyValidate(t *testing.T) {
+	p, err := NewPolicy(testPolicyConfig())
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"valid", "Correct-Horse-7", nil},
+		{"too short", "Ab1!", ErrTooShort},
+		{"length counts runes, not bytes", "Äbcdéfg1!", ErrTooShort},
+		{"missing upper", "correct-horse-7", ErrMissingUpper},
+		{"missing lower", "CORRECT-HORSE-7", ErrMissingLower},
+		{"missing digit", "Correct-Horse-!", ErrMissingDigit},
+		{"missing symbol", "CorrectHorse77", ErrMissingSymbol},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.Validate(tt.password); !errors.Is(err, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyValidateDenied(t *testing.T) {
+	cfg := config.PasswordConfig{
+		MinLength:         1,
+		Argon2Memory:      64,
+		Argon2Iterations:  1,
+		Argon2Parallelism: 1,
+	}
+	cfg.DenyListFile = filepath.Join(t.TempDir(), "deny.txt")
+	if err := os.WriteFile(cfg.DenyListFile, []byte("  Company2024 \n\nhunter2\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewPolicy(cfg)
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"built-in list", "password", ErrTooCommon},
+		{"built-in list ignores case", "PassWord", ErrTooCommon},
+		{"deny-list file", "hunter2", ErrTooCommon},
+		{"deny-list file is trimmed and ignores case", "company2024", ErrTooCommon},
+		{"not denied", "company2025", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.Validate(tt.password); !errors.Is(err, tt.want) {
+				t.Errorf("Validate(%q) = %v, want %v", tt.password, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPolicyErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.PasswordConfig)
+	}{
+		{"no argon2 memory", func(c *config.PasswordConfig) { c.Argon2Memory = 0 }},
+		{"no argon2 iterations", func(c *config.PasswordConfig) { c.Argon2Iterations = 0 }},
+		{"no argon2 parallelism", func(c *config.PasswordConfig) { c.Argon2Parallelism = 0 }},
+		{"argon2 parallelism above 255", func(c *config.PasswordConfig) { c.Argon2Parallelism = 256 }},
+		{"missing deny-list file", func(c *config.PasswordConfig) { c.DenyListFile = filepath.Join(t.TempDir(), "missing.txt") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testPolicyConfig()
+			tt.modify(&cfg)
+			if _, err := NewPolicy(cfg); err == nil {
+				t.Error("NewPolicy succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestPolicyCheckReuse(t *testing.T) {
+	p, err := NewPolicy(testPolicyConfig())
+	if err != nil {
+		t.Fatalf("NewPolicy: %v", err)
+	}
+	var hashes []string
+	for _, pw := range []string{"Newest-Pass-1", "Older-Pass-2", "Oldest-Pass-3"} {
+		hash, err := p.Hash(pw)
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"newest", "Newest-Pass-1", ErrReused},
+		{"within history", "Older-Pass-2", ErrReused},
+		{"beyond history", "Oldest-Pass-3", nil},
+		{"never used", "Fresh-Pass-4", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := p.CheckReuse(tt.password, hashes); !errors.Is(err, tt.want) {
+				t.Errorf("CheckReuse(%q) = %v, want %v", tt.password, err, tt.want)
+			}
+		})
+	}
+}
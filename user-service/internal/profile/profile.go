@@ -0,0 +1,66 @@
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+	// Timezones are validated against the embedded database, so it doesn't
+	// depend on the image having tzdata installed
+	_ "time/tzdata"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+const (
+	// MaxTextLength bounds free text fields such as the display name.
+	MaxTextLength      = 100
+	maxAvatarURLLength = 500
+)
+
+// The Normalize functions validate a non-empty profile field and return it in
+// the form it is stored in. They are shared by GraphQL and SCIM.
+
+func NormalizeText(field, v string) (string, error) {
+	if utf8.RuneCountInString(v) > MaxTextLength {
+		return "", fmt.Errorf("%s must be at most %d characters", field, MaxTextLength)
+	}
+	return v, nil
+}
+
+// Text returns NormalizeText for field, naming it in errors.
+func Text(field string) func(string) (string, error) {
+	return func(v string) (string, error) {
+		return NormalizeText(field, v)
+	}
+}
+
+func NormalizeAvatarURL(v string) (string, error) {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(v) > maxAvatarURLLength {
+		return "", errors.New("avatar URL must be an http(s) URL")
+	}
+	return u.String(), nil
+}
+
+// NormalizeTimezone accepts IANA names such as Europe/Paris.
+func NormalizeTimezone(v string) (string, error) {
+	if v == "Local" {
+		return "", errors.New("unknown timezone")
+	}
+	loc, err := time.LoadLocation(v)
+	if err != nil {
+		return "", errors.New("unknown timezone")
+	}
+	return loc.String(), nil
+}
+
+// NormalizeLocale accepts BCP 47 language tags such as en-US.
+func NormalizeLocale(v string) (string, error) {
+	tag, err := language.Parse(v)
+	if err != nil {
+		return "", errors.New("locale must be a BCP 47 language tag")
+	}
+	return tag.String(), nil
+}
@@ -0,0 +1,103 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"user-service/internal/model"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userChangesChannel = "user.changes"
+	// subscriberBuffer is how many changes a slow subscriber may lag behind
+	// before further changes are dropped for it.
+	subscriberBuffer = 16
+)
+
+// UserChange is a user lifecycle change as delivered to subscribers.
+type UserChange struct {
+	Type        string     `json:"type"`
+	User        model.User `json:"user"`
+	PerformedBy string     `json:"performedBy,omitempty"`
+	// ActedBy is the admin who made the change impersonating PerformedBy
+	ActedBy    string    `json:"actedBy,omitempty"`
+	OccurredAt time.Time `json:"occurredAt"`
+}
+
+// UserChanges fans user changes out to the subscribers of every replica.
+// Changes go through one Redis pub/sub channel, which each replica relays to
+// its local subscribers.
+type UserChanges struct {
+	redis *redis.Client
+
+	mu          sync.Mutex
+	subscribers map[chan *UserChange]struct{}
+}
+
+func NewUserChanges(client *redis.Client) *UserChanges {
+	return &UserChanges{
+		redis:       client,
+		subscribers: make(map[chan *UserChange]struct{}),
+	}
+}
+
+// Publish sends change to all subscribers, on this and other replicas.
+func (u *UserChanges) Publish(ctx context.Context, change *UserChange) error {
+	data, err := json.Marshal(change)
+	if err != nil {
+		return err
+	}
+	return u.redis.Publish(ctx, userChangesChannel, data).Err()
+}
+
+// Run relays changes from Redis to local subscribers until ctx is done.
+func (u *UserChanges) Run(ctx context.Context) {
+	sub := u.redis.Subscribe(ctx, userChangesChannel)
+	defer sub.Close()
+
+	for msg := range sub.Channel() {
+		var change UserChange
+		if err := json.Unmarshal([]byte(msg.Payload), &change); err != nil {
+			slog.Error("Failed to decode user change", "error", err)
+			continue
+		}
+		u.broadcast(&change)
+	}
+}
+
+// Subscribe returns the changes published from now on. unsubscribe has to be
+// called once the changes are no longer read.
+func (u *UserChanges) Subscribe() (changes <-chan *UserChange, unsubscribe func()) {
+	ch := make(chan *UserChange, subscriberBuffer)
+
+	u.mu.Lock()
+	u.subscribers[ch] = struct{}{}
+	u.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			u.mu.Lock()
+			delete(u.subscribers, ch)
+			u.mu.Unlock()
+		})
+	}
+}
+
+func (u *UserChanges) broadcast(change *UserChange) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for ch := range u.subscribers {
+		select {
+		case ch <- change:
+		default:
+			slog.Warn("Dropped user change for slow subscriber", "type", change.Type, "userId", change.User.UserID)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package scim
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type filterSupport struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+// serviceProviderConfig tells clients which features are supported.
+func (h *Handler) serviceProviderConfig(c *gin.Context) {
+	writeJSON(c, http.StatusOK, gin.H{
+		"schemas":        []string{schemaServiceProviderConfig},
+		"patch":          supported{Supported: true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         filterSupport{Supported: true, MaxResults: maxCount},
+		"changePassword": supported{Supported: true},
+		"sort":           supported{Supported: false},
+		"etag":           supported{Supported: false},
+		"authenticationSchemes": []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "API key",
+			Description: "API key of a service account with the users:provision scope",
+			Primary:     true,
+		}},
+		"meta": meta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     resourceURL(c, "ServiceProviderConfig"),
+		},
+	})
+}
+
+func (h *Handler) resourceTypes(c *gin.Context) {
+	resourceType := func(name, endpoint, schema string) gin.H {
+		return gin.H{
+			"schemas":  []string{schemaResourceType},
+			"id":       name,
+			"name":     name,
+			"endpoint": endpoint,
+			"schema":   schema,
+			"meta": meta{
+				ResourceType: "ResourceType",
+				Location:     resourceURL(c, "ResourceTypes", name),
+			},
+		}
+	}
+	resources := []gin.H{
+		resourceType("User", "/Users", schemaUser),
+		resourceType("Group", "/Groups", schemaGroup),
+	}
+	writeJSON(c, http.StatusOK, listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: int64(len(resources)),
+		StartIndex:   1,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
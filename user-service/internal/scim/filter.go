@@ -0,0 +1,452 @@
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"user-service/internal/database"
+)
+
+// filter is a parsed SCIM filter expression (RFC 7644 section 3.4.2.2).
+type filter interface{}
+
+// logicalFilter joins two filters with "and" or "or".
+type logicalFilter struct {
+	op          string
+	left, right filter
+}
+
+type notFilter struct {
+	inner filter
+}
+
+// attrFilter compares an attribute with a value. attr is lower case and
+// fully qualified for sub-attributes ("emails.value"); value is nil for "pr".
+type attrFilter struct {
+	attr  string
+	op    string
+	value interface{}
+}
+
+var comparisonOps = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true,
+	"gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// parseFilter parses a filter. Attribute names may carry the core schema URN
+// and are matched case-insensitively, as the RFC requires.
+func parseFilter(s string) (filter, error) {
+	tokens, err := tokenizeFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+type filterToken struct {
+	text string
+	// quoted is set for string literals, whose text is already unquoted
+	quoted bool
+}
+
+func tokenizeFilter(s string) ([]filterToken, error) {
+	var tokens []filterToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == '(' || c == ')' || c == '[' || c == ']':
+			tokens = append(tokens, filterToken{text: string(c)})
+			i++
+		case c == '"':
+			// Find the closing quote, skipping escaped characters
+			j := i + 1
+			for j < len(s) && s[j] != '"' {
+				if s[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			if j >= len(s) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			var str string
+			if err := json.Unmarshal([]byte(s[i:j+1]), &str); err != nil {
+				return nil, fmt.Errorf("invalid string %s", s[i:j+1])
+			}
+			tokens = append(tokens, filterToken{text: str, quoted: true})
+			i = j + 1
+		default:
+			j := i
+			for j < len(s) && !unicode.IsSpace(rune(s[j])) && !strings.ContainsRune("()[]\"", rune(s[j])) {
+				j++
+			}
+			tokens = append(tokens, filterToken{text: s[i:j]})
+			i = j
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []filterToken
+	pos    int
+}
+
+func (p *filterParser) peek() (filterToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return filterToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *filterParser) next() (filterToken, error) {
+	t, ok := p.peek()
+	if !ok {
+		return t, fmt.Errorf("unexpected end of filter")
+	}
+	p.pos++
+	return t, nil
+}
+
+// isKeyword reports whether the next token is the unquoted keyword kw.
+func (p *filterParser) isKeyword(kw string) bool {
+	t, ok := p.peek()
+	return ok && !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+func (p *filterParser) expect(text string) error {
+	t, err := p.next()
+	if err != nil {
+		return err
+	}
+	if t.quoted || t.text != text {
+		return fmt.Errorf("expected %q, got %q", text, t.text)
+	}
+	return nil
+}
+
+// The parse functions take the attribute prefix of an enclosing value path,
+// so emails[type eq "work"] yields the attribute "emails.type".
+
+func (p *filterParser) parseOr(prefix string) (filter, error) {
+	left, err := p.parseAnd(prefix)
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		p.pos++
+		right, err := p.parseAnd(prefix)
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{op: "or", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd(prefix string) (filter, error) {
+	left, err := p.parseFactor(prefix)
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("and") {
+		p.pos++
+		right, err := p.parseFactor(prefix)
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalFilter{op: "and", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseFactor(prefix string) (filter, error) {
+	if p.isKeyword("not") {
+		p.pos++
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		inner, err := p.parseOr(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{inner: inner}, p.expect(")")
+	}
+	if t, ok := p.peek(); ok && !t.quoted && t.text == "(" {
+		p.pos++
+		inner, err := p.parseOr(prefix)
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+
+	t, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	if t.quoted {
+		return nil, fmt.Errorf("expected an attribute, got %q", t.text)
+	}
+	attr := prefix + normalizeAttr(t.text)
+
+	// Value path: attr[filter]
+	if next, ok := p.peek(); ok && !next.quoted && next.text == "[" {
+		if prefix != "" {
+			return nil, fmt.Errorf("nested value paths are not supported")
+		}
+		p.pos++
+		inner, err := p.parseOr(attr + ".")
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect("]")
+	}
+
+	opToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	op := strings.ToLower(opToken.text)
+	if op == "pr" {
+		return &attrFilter{attr: attr, op: op}, nil
+	}
+	if opToken.quoted || !comparisonOps[op] {
+		return nil, fmt.Errorf("unknown operator %q", opToken.text)
+	}
+
+	valueToken, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	value, err := filterValue(valueToken)
+	if err != nil {
+		return nil, err
+	}
+	return &attrFilter{attr: attr, op: op, value: value}, nil
+}
+
+func filterValue(t filterToken) (interface{}, error) {
+	if t.quoted {
+		return t.text, nil
+	}
+	switch strings.ToLower(t.text) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal([]byte(t.text), &n); err != nil {
+		return nil, fmt.Errorf("invalid value %q", t.text)
+	}
+	return n, nil
+}
+
+// normalizeAttr lower-cases an attribute path and strips the core schema URN.
+func normalizeAttr(attr string) string {
+	attr = strings.ToLower(attr)
+	for _, urn := range []string{schemaUser, schemaGroup} {
+		attr = strings.TrimPrefix(attr, strings.ToLower(urn)+":")
+	}
+	return attr
+}
+
+// column describes how a filterable attribute is stored.
+type column struct {
+	sql       string
+	kind      string // "string", "bool" or "time"
+	caseExact bool
+}
+
+// sqlFilter translates f into a WHERE clause over columns.
+func sqlFilter(f filter, columns map[string]column) (string, []interface{}, error) {
+	switch f := f.(type) {
+	case *logicalFilter:
+		left, leftArgs, err := sqlFilter(f.left, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		right, rightArgs, err := sqlFilter(f.right, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "(" + left + " " + strings.ToUpper(f.op) + " " + right + ")", append(leftArgs, rightArgs...), nil
+	case *notFilter:
+		inner, args, err := sqlFilter(f.inner, columns)
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT " + inner, args, nil
+	case *attrFilter:
+		col, ok := columns[f.attr]
+		if !ok {
+			return "", nil, fmt.Errorf("filtering on %q is not supported", f.attr)
+		}
+		return sqlComparison(col, f)
+	}
+	return "", nil, fmt.Errorf("invalid filter")
+}
+
+func sqlComparison(col column, f *attrFilter) (string, []interface{}, error) {
+	if f.op == "pr" {
+		switch col.kind {
+		case "bool":
+			return "TRUE", nil, nil
+		case "string":
+			return "(" + col.sql + " IS NOT NULL AND " + col.sql + " <> '')", nil, nil
+		}
+		return col.sql + " IS NOT NULL", nil, nil
+	}
+
+	switch col.kind {
+	case "bool":
+		b, ok := f.value.(bool)
+		if !ok || (f.op != "eq" && f.op != "ne") {
+			return "", nil, fmt.Errorf("%q only supports eq and ne with a boolean", f.attr)
+		}
+		if f.op == "ne" {
+			b = !b
+		}
+		if b {
+			return col.sql, nil, nil
+		}
+		return "NOT " + col.sql, nil, nil
+
+	case "time":
+		s, ok := f.value.(string)
+		if !ok {
+			return "", nil, fmt.Errorf("%q must be compared with a date-time", f.attr)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return "", nil, fmt.Errorf("%q must be compared with a date-time", f.attr)
+		}
+		op, ok := sqlOrderOps[f.op]
+		if !ok {
+			return "", nil, fmt.Errorf("%q doesn't support %s", f.attr, f.op)
+		}
+		return col.sql + " " + op + " ?", []interface{}{t}, nil
+	}
+
+	s, ok := f.value.(string)
+	if !ok {
+		return "", nil, fmt.Errorf("%q must be compared with a string", f.attr)
+	}
+	expr, arg := col.sql, s
+	if !col.caseExact {
+		expr, arg = "LOWER("+col.sql+")", strings.ToLower(s)
+	}
+	switch f.op {
+	case "co":
+		return expr + ` LIKE ? ESCAPE '\'`, []interface{}{"%" + database.EscapeLike(arg) + "%"}, nil
+	case "sw":
+		return expr + ` LIKE ? ESCAPE '\'`, []interface{}{database.EscapeLike(arg) + "%"}, nil
+	case "ew":
+		return expr + ` LIKE ? ESCAPE '\'`, []interface{}{"%" + database.EscapeLike(arg)}, nil
+	}
+	return expr + " " + sqlOrderOps[f.op] + " ?", []interface{}{arg}, nil
+}
+
+var sqlOrderOps = map[string]string{
+	"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<=",
+}
+
+// matchFilter evaluates f in memory, reading attributes with get. Only string
+// and boolean attributes are supported; strings compare case-insensitively.
+// get returns a slice for multi-valued attributes, which match when any of
+// their values does.
+func matchFilter(f filter, get func(attr string) (interface{}, bool)) (bool, error) {
+	switch f := f.(type) {
+	case *logicalFilter:
+		left, err := matchFilter(f.left, get)
+		if err != nil {
+			return false, err
+		}
+		right, err := matchFilter(f.right, get)
+		if err != nil {
+			return false, err
+		}
+		if f.op == "and" {
+			return left && right, nil
+		}
+		return left || right, nil
+	case *notFilter:
+		inner, err := matchFilter(f.inner, get)
+		return !inner, err
+	case *attrFilter:
+		actual, ok := get(f.attr)
+		if f.op == "pr" {
+			if values, multi := actual.([]interface{}); multi {
+				return len(values) > 0, nil
+			}
+			return ok && actual != nil && actual != "", nil
+		}
+		if !ok {
+			return false, nil
+		}
+		if values, multi := actual.([]interface{}); multi {
+			for _, v := range values {
+				if matched, err := compareValues(v, f); err != nil || matched {
+					return matched, err
+				}
+			}
+			return false, nil
+		}
+		return compareValues(actual, f)
+	}
+	return false, fmt.Errorf("invalid filter")
+}
+
+func compareValues(actual interface{}, f *attrFilter) (bool, error) {
+	if b, ok := actual.(bool); ok {
+		want, ok := f.value.(bool)
+		if !ok || (f.op != "eq" && f.op != "ne") {
+			return false, fmt.Errorf("%q only supports eq and ne with a boolean", f.attr)
+		}
+		return (b == want) == (f.op == "eq"), nil
+	}
+
+	a, aok := actual.(string)
+	v, vok := f.value.(string)
+	if !aok || !vok {
+		return false, fmt.Errorf("%q must be compared with a string", f.attr)
+	}
+	a, v = strings.ToLower(a), strings.ToLower(v)
+	switch f.op {
+	case "eq":
+		return a == v, nil
+	case "ne":
+		return a != v, nil
+	case "co":
+		return strings.Contains(a, v), nil
+	case "sw":
+		return strings.HasPrefix(a, v), nil
+	case "ew":
+		return strings.HasSuffix(a, v), nil
+	case "gt":
+		return a > v, nil
+	case "ge":
+		return a >= v, nil
+	case "lt":
+		return a < v, nil
+	case "le":
+		return a <= v, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", f.op)
+}
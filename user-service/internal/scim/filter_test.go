@@ -0,0 +1,289 @@
+package scim
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSQLFilter(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		filter    string
+		wantWhere string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "case-insensitive string",
+			filter:    `userName eq "Ann@Example.com"`,
+			wantWhere: "LOWER(email) = ?",
+			wantArgs:  []interface{}{"ann@example.com"},
+		},
+		{
+			name:      "case-exact string",
+			filter:    `externalId eq "AbC"`,
+			wantWhere: "external_id = ?",
+			wantArgs:  []interface{}{"AbC"},
+		},
+		{
+			name:      "attribute names ignore case and the schema URN",
+			filter:    `urn:ietf:params:scim:schemas:core:2.0:User:USERNAME ne "ann@example.com"`,
+			wantWhere: "LOWER(email) <> ?",
+			wantArgs:  []interface{}{"ann@example.com"},
+		},
+		{
+			name:      "operators ignore case",
+			filter:    `displayName GE "m"`,
+			wantWhere: "LOWER(display_name) >= ?",
+			wantArgs:  []interface{}{"m"},
+		},
+		{
+			name:      "contains escapes wildcards",
+			filter:    `userName co "100%_\\"`,
+			wantWhere: `LOWER(email) LIKE ? ESCAPE '\'`,
+			wantArgs:  []interface{}{`%100\%\_\\%`},
+		},
+		{
+			name:      "starts with",
+			filter:    `title sw "Eng"`,
+			wantWhere: `LOWER(job_title) LIKE ? ESCAPE '\'`,
+			wantArgs:  []interface{}{"eng%"},
+		},
+		{
+			name:      "ends with",
+			filter:    `userName ew "@example.com"`,
+			wantWhere: `LOWER(email) LIKE ? ESCAPE '\'`,
+			wantArgs:  []interface{}{"%@example.com"},
+		},
+		{
+			name:      "escaped quote in string",
+			filter:    `displayName eq "Ann \"The Admin\""`,
+			wantWhere: "LOWER(display_name) = ?",
+			wantArgs:  []interface{}{`ann "the admin"`},
+		},
+		{
+			name:      "present string",
+			filter:    `title pr`,
+			wantWhere: "(job_title IS NOT NULL AND job_title <> '')",
+		},
+		{
+			name:      "present time",
+			filter:    `meta.created pr`,
+			wantWhere: "created_at IS NOT NULL",
+		},
+		{
+			name:      "boolean true",
+			filter:    `active eq true`,
+			wantWhere: "(status = 'active')",
+		},
+		{
+			name:      "boolean false",
+			filter:    `active eq False`,
+			wantWhere: "NOT (status = 'active')",
+		},
+		{
+			name:      "boolean not equal",
+			filter:    `active ne false`,
+			wantWhere: "(status = 'active')",
+		},
+		{
+			name:      "date-time",
+			filter:    `meta.created gt "2024-01-02T03:04:05Z"`,
+			wantWhere: "created_at > ?",
+			wantArgs:  []interface{}{created},
+		},
+		{
+			name:      "and binds tighter than or",
+			filter:    `title eq "a" or title eq "b" and locale eq "c"`,
+			wantWhere: "(LOWER(job_title) = ? OR (LOWER(job_title) = ? AND LOWER(locale) = ?))",
+			wantArgs:  []interface{}{"a", "b", "c"},
+		},
+		{
+			name:      "grouping",
+			filter:    `(title eq "a" or title eq "b") and locale eq "c"`,
+			wantWhere: "((LOWER(job_title) = ? OR LOWER(job_title) = ?) AND LOWER(locale) = ?)",
+			wantArgs:  []interface{}{"a", "b", "c"},
+		},
+		{
+			name:      "not",
+			filter:    `not (title pr) AND active eq true`,
+			wantWhere: "(NOT (job_title IS NOT NULL AND job_title <> '') AND (status = 'active'))",
+		},
+		{
+			name:      "value path",
+			filter:    `emails[type eq "work" and value ew "@example.com"]`,
+			wantWhere: `(LOWER('work') = ? AND LOWER(email) LIKE ? ESCAPE '\')`,
+			wantArgs:  []interface{}{"work", "%@example.com"},
+		},
+		{
+			name:      "sub-attribute",
+			filter:    `emails.value eq "ann@example.com"`,
+			wantWhere: "LOWER(email) = ?",
+			wantArgs:  []interface{}{"ann@example.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q): %v", tt.filter, err)
+			}
+			where, args, err := sqlFilter(f, userColumns)
+			if err != nil {
+				t.Fatalf("sqlFilter(%q): %v", tt.filter, err)
+			}
+			if where != tt.wantWhere {
+				t.Errorf("where = %s, want %s", where, tt.wantWhere)
+			}
+			if len(args) != 0 || len(tt.wantArgs) != 0 {
+				if !reflect.DeepEqual(args, tt.wantArgs) {
+					t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+				}
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"empty", ``},
+		{"unterminated string", `userName eq "ann`},
+		{"invalid escape", `userName eq "\x"`},
+		{"unknown operator", `userName is "ann"`},
+		{"quoted operator", `userName "eq" "ann"`},
+		{"missing value", `userName eq`},
+		{"invalid value", `userName eq ann`},
+		{"quoted attribute", `"userName" eq "ann"`},
+		{"unclosed group", `(userName eq "ann"`},
+		{"not without group", `not userName eq "ann"`},
+		{"unclosed value path", `emails[type eq "work"`},
+		{"nested value path", `emails[type[value eq "x"]]`},
+		{"trailing tokens", `userName eq "ann" title`},
+		{"dangling and", `userName eq "ann" and`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if f, err := parseFilter(tt.filter); err == nil {
+				t.Errorf("parseFilter(%q) = %#v, want an error", tt.filter, f)
+			}
+		})
+	}
+}
+
+func TestSQLFilterErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"unknown attribute", `password eq "secret"`},
+		{"boolean compared with a string", `active eq "true"`},
+		{"boolean with an order operator", `active gt true`},
+		{"string compared with a boolean", `userName eq true`},
+		{"string compared with a number", `userName eq 42`},
+		{"string compared with null", `userName eq null`},
+		{"date-time compared with a number", `meta.created gt 2024`},
+		{"malformed date-time", `meta.created gt "yesterday"`},
+		{"date-time with contains", `meta.created co "2024"`},
+		{"unknown attribute in a logical filter", `userName eq "ann" or password eq "secret"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q): %v", tt.filter, err)
+			}
+			if where, _, err := sqlFilter(f, userColumns); err == nil {
+				t.Errorf("sqlFilter(%q) = %s, want an error", tt.filter, where)
+			}
+		})
+	}
+}
+
+func TestMatchFilter(t *testing.T) {
+	group := &groupResource{
+		ID: "manager",
+		Members: []multiValue{
+			{Value: "3f1c6a1e-0000-0000-0000-000000000001"},
+			{Value: "3f1c6a1e-0000-0000-0000-000000000002"},
+		},
+	}
+	empty := &groupResource{ID: "admin"}
+
+	tests := []struct {
+		name   string
+		group  *groupResource
+		filter string
+		want   bool
+	}{
+		{"equal", group, `displayName eq "manager"`, true},
+		{"equal ignores case", group, `displayName eq "Manager"`, true},
+		{"not equal", group, `displayName ne "manager"`, false},
+		{"starts with", group, `displayName sw "man"`, true},
+		{"ends with", group, `displayName ew "ger"`, true},
+		{"contains", group, `displayName co "nag"`, true},
+		{"greater than", group, `displayName gt "admin"`, true},
+		{"less than", group, `displayName lt "admin"`, false},
+		{"any member matches", group, `members eq "3f1c6a1e-0000-0000-0000-000000000002"`, true},
+		{"no member matches", group, `members.value eq "3f1c6a1e-0000-0000-0000-000000000003"`, false},
+		{"value path", group, `members[value eq "3f1c6a1e-0000-0000-0000-000000000001"]`, true},
+		{"members present", group, `members pr`, true},
+		{"no members present", empty, `members pr`, false},
+		{"unknown attribute", group, `externalId eq "x"`, false},
+		{"unknown attribute present", group, `externalId pr`, false},
+		{"and", group, `displayName eq "manager" and members pr`, true},
+		{"or", empty, `displayName eq "manager" or displayName eq "admin"`, true},
+		{"not", empty, `not (displayName eq "manager")`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q): %v", tt.filter, err)
+			}
+			got, err := matchFilter(f, groupAttr(tt.group))
+			if err != nil {
+				t.Fatalf("matchFilter(%q): %v", tt.filter, err)
+			}
+			if got != tt.want {
+				t.Errorf("matchFilter(%q) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchFilterErrors(t *testing.T) {
+	get := func(attr string) (interface{}, bool) {
+		switch attr {
+		case "displayname":
+			return "manager", true
+		case "primary":
+			return true, true
+		}
+		return nil, false
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+	}{
+		{"string compared with a boolean", `displayName eq true`},
+		{"boolean compared with a string", `primary eq "true"`},
+		{"boolean with an order operator", `primary gt false`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := parseFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("parseFilter(%q): %v", tt.filter, err)
+			}
+			if _, err := matchFilter(f, get); err == nil {
+				t.Errorf("matchFilter(%q) succeeded, want an error", tt.filter)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+package scim
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"user-service/internal/events"
+	"user-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// roleGroups are the groups, one per role. A group's id and displayName are
+// the role and its members the users with that role. Adding a user to a group
+// assigns them the role; removing them reverts them to member, so removals
+// from the member group have no effect.
+var roleGroups = []string{model.RoleAdmin, model.RoleManager, model.RoleMember}
+
+func (h *Handler) listGroups(c *gin.Context) {
+	startIndex, count, err := pagination(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	var f filter
+	if s := c.Query("filter"); s != "" {
+		if f, err = parseFilter(s); err != nil {
+			fail(c, badRequest(errInvalidFilter, err.Error()))
+			return
+		}
+	}
+	withMembers := !excludesMembers(c)
+
+	var matching []*groupResource
+	for _, role := range roleGroups {
+		group, err := h.loadGroup(c, role, withMembers || f != nil)
+		if err != nil {
+			fail(c, err)
+			return
+		}
+		if f != nil {
+			ok, err := matchFilter(f, groupAttr(group))
+			if err != nil {
+				fail(c, badRequest(errInvalidFilter, err.Error()))
+				return
+			}
+			if !ok {
+				continue
+			}
+		}
+		if !withMembers {
+			group.Members = nil
+		}
+		matching = append(matching, group)
+	}
+
+	page := []*groupResource{}
+	if startIndex <= len(matching) {
+		page = matching[startIndex-1 : min(startIndex-1+count, len(matching))]
+	}
+	writeJSON(c, http.StatusOK, listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: int64(len(matching)),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	})
+}
+
+// excludesMembers reports whether the client asked to leave members out, as
+// identity providers do when they only look groups up.
+func excludesMembers(c *gin.Context) bool {
+	for _, attr := range strings.Split(c.Query("excludedAttributes"), ",") {
+		if normalizeAttr(strings.TrimSpace(attr)) == "members" {
+			return true
+		}
+	}
+	return false
+}
+
+func groupAttr(group *groupResource) func(attr string) (interface{}, bool) {
+	return func(attr string) (interface{}, bool) {
+		switch attr {
+		case "id", "displayname":
+			return group.ID, true
+		case "members", "members.value":
+			ids := make([]interface{}, len(group.Members))
+			for i, m := range group.Members {
+				ids[i] = m.Value
+			}
+			return ids, true
+		}
+		return nil, false
+	}
+}
+
+func (h *Handler) getGroup(c *gin.Context) {
+	role, err := groupRole(c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	group, err := h.loadGroup(c, role, !excludesMembers(c))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, group)
+}
+
+func groupRole(id string) (string, error) {
+	if !slices.Contains(roleGroups, id) {
+		return "", notFound("group not found")
+	}
+	return id, nil
+}
+
+func (h *Handler) loadGroup(c *gin.Context, role string, withMembers bool) (*groupResource, error) {
+	group := &groupResource{
+		Schemas:     []string{schemaGroup},
+		ID:          role,
+		DisplayName: role,
+		Members:     []multiValue{},
+		Meta: &meta{
+			ResourceType: "Group",
+			Location:     resourceURL(c, "Groups", role),
+		},
+	}
+	if !withMembers {
+		return group, nil
+	}
+
+	var users []model.User
+	if err := h.db.WithContext(c.Request.Context()).
+		Select("user_id", "email").
+		Where("role = ?", role).
+		Order("created_at, user_id").
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to load group members: %w", err)
+	}
+	for _, u := range users {
+		group.Members = append(group.Members, multiValue{
+			Value:   u.UserID,
+			Display: u.Email,
+			Ref:     resourceURL(c, "Users", u.UserID),
+		})
+	}
+	return group, nil
+}
+
+// replaceGroup sets the members of a group.
+func (h *Handler) replaceGroup(c *gin.Context) {
+	role, err := groupRole(c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	var res groupResource
+	if err := bind(c, &res); err != nil {
+		fail(c, err)
+		return
+	}
+	h.updateGroup(c, role, &res)
+}
+
+func (h *Handler) patchGroup(c *gin.Context) {
+	role, err := groupRole(c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	req, err := bindPatch(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	current, err := h.loadGroup(c, role, true)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	doc, err := toMap(current)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if err := applyPatch(doc, req.Operations); err != nil {
+		fail(c, err)
+		return
+	}
+	var res groupResource
+	if err := fromMap(doc, &res); err != nil {
+		fail(c, err)
+		return
+	}
+	h.updateGroup(c, role, &res)
+}
+
+// updateGroup changes the roles of the users joining and leaving a group to
+// match res.
+func (h *Handler) updateGroup(c *gin.Context, role string, res *groupResource) {
+	if res.DisplayName != "" && res.DisplayName != role {
+		fail(c, &requestError{
+			status:   http.StatusBadRequest,
+			scimType: errMutability,
+			detail:   "groups can't be renamed",
+		})
+		return
+	}
+
+	current, err := h.loadGroup(c, role, true)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	members := make(map[string]bool, len(current.Members))
+	for _, m := range current.Members {
+		members[m.Value] = true
+	}
+	wanted := make(map[string]bool, len(res.Members))
+	for _, m := range res.Members {
+		if _, err := uuid.Parse(m.Value); err != nil {
+			fail(c, badRequest(errInvalidValue, "unknown member "+m.Value))
+			return
+		}
+		wanted[m.Value] = true
+	}
+
+	// New roles, applied all at once so a failure leaves the group unchanged
+	roles := make(map[string]string)
+	for id := range wanted {
+		if !members[id] {
+			roles[id] = role
+		}
+	}
+	if role != model.RoleMember {
+		for id := range members {
+			if !wanted[id] {
+				roles[id] = model.RoleMember
+			}
+		}
+	}
+	if err := h.setRoles(c, roles); err != nil {
+		fail(c, err)
+		return
+	}
+
+	group, err := h.loadGroup(c, role, true)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, group)
+}
+
+// setRoles changes the roles of users, by user ID, in one transaction and then
+// runs the side effects of the changeRole mutation.
+func (h *Handler) setRoles(c *gin.Context, roles map[string]string) error {
+	ctx := c.Request.Context()
+
+	var changed []*model.User
+	err := h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for userID, role := range roles {
+			var user model.User
+			if err := tx.Where("user_id = ?", userID).First(&user).Error; err != nil {
+				return badRequest(errInvalidValue, "unknown member "+userID)
+			}
+			if err := tx.Model(&user).Update("role", role).Error; err != nil {
+				return fmt.Errorf("failed to change role: %w", err)
+			}
+			changed = append(changed, &user)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, user := range changed {
+		// Access tokens carry the role, so force a refresh to pick up the new one
+		if err := h.revocations.RevokeUser(ctx, user.UserID); err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		h.events.Publish(ctx, events.UserRoleChanged, user, performedBy(c))
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+// Package scim serves SCIM 2.0 (RFC 7643, RFC 7644) provisioning endpoints, so
+// identity providers can manage users and their roles. Users map onto
+// model.User and groups onto the fixed roles; every change goes through the
+// same lifecycle as the GraphQL mutations.
+package scim
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"user-service/internal/audit"
+	"user-service/internal/auth"
+	"user-service/internal/events"
+	"user-service/internal/password"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	// basePath is where the endpoints are mounted, and the base of resource
+	// locations.
+	basePath = "/scim/v2"
+	// contentType is the media type of SCIM requests and responses.
+	contentType = "application/scim+json"
+
+	principalKey = "scimPrincipal"
+)
+
+// Handler serves the SCIM endpoints. Callers authenticate with the API key of
+// a service account holding the users:provision scope.
+type Handler struct {
+	db          *gorm.DB
+	revocations *auth.RevocationStore
+	apiKeys     *auth.APIKeyStore
+	passwords   *password.Policy
+	events      *events.UserEvents
+	audit       *audit.Trail
+}
+
+func NewHandler(db *gorm.DB, revocations *auth.RevocationStore, apiKeys *auth.APIKeyStore, passwords *password.Policy, userEvents *events.UserEvents, auditTrail *audit.Trail) *Handler {
+	return &Handler{
+		db:          db,
+		revocations: revocations,
+		apiKeys:     apiKeys,
+		passwords:   passwords,
+		events:      userEvents,
+		audit:       auditTrail,
+	}
+}
+
+// Register mounts the endpoints under /scim/v2 on r.
+func (h *Handler) Register(r gin.IRouter) {
+	g := r.Group(basePath, h.authenticate)
+
+	g.GET("/ServiceProviderConfig", h.serviceProviderConfig)
+	g.GET("/ResourceTypes", h.resourceTypes)
+
+	g.GET("/Users", h.listUsers)
+	g.POST("/Users", h.createUser)
+	g.GET("/Users/:id", h.getUser)
+	g.PUT("/Users/:id", h.replaceUser)
+	g.PATCH("/Users/:id", h.patchUser)
+	g.DELETE("/Users/:id", h.deleteUser)
+
+	g.GET("/Groups", h.listGroups)
+	g.POST("/Groups", h.groupsAreFixed)
+	g.GET("/Groups/:id", h.getGroup)
+	g.PUT("/Groups/:id", h.replaceGroup)
+	g.PATCH("/Groups/:id", h.patchGroup)
+	g.DELETE("/Groups/:id", h.groupsAreFixed)
+}
+
+// authenticate only lets service accounts with the users:provision scope
+// through. Unlike the GraphQL endpoint, access tokens of users are refused.
+func (h *Handler) authenticate(c *gin.Context) {
+	credential, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || !auth.IsAPIKey(credential) {
+		c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+		writeError(c, http.StatusUnauthorized, "", "an API key is required")
+		c.Abort()
+		return
+	}
+
+	principal, err := h.apiKeys.Authenticate(c.Request.Context(), credential)
+	if err != nil {
+		c.Header("WWW-Authenticate", `Bearer realm="scim"`)
+		writeError(c, http.StatusUnauthorized, "", "invalid API key")
+		c.Abort()
+		return
+	}
+	if !principal.HasScope(auth.ScopeUsersProvision) {
+		writeError(c, http.StatusForbidden, "", "missing scope "+auth.ScopeUsersProvision)
+		c.Abort()
+		return
+	}
+
+	c.Set(principalKey, principal)
+	c.Next()
+}
+
+// performedBy is recorded in the events of changes made by the caller.
+func performedBy(c *gin.Context) string {
+	return c.MustGet(principalKey).(*auth.ServicePrincipal).ID
+}
+
+// scimError is the body of error responses (RFC 7644 section 3.12).
+type scimError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+// Error types used in scimType.
+const (
+	errInvalidFilter = "invalidFilter"
+	errInvalidSyntax = "invalidSyntax"
+	errInvalidPath   = "invalidPath"
+	errInvalidValue  = "invalidValue"
+	errNoTarget      = "noTarget"
+	errUniqueness    = "uniqueness"
+	errMutability    = "mutability"
+)
+
+// requestError is a client error surfaced as a SCIM error response.
+type requestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *requestError) Error() string {
+	return e.detail
+}
+
+func badRequest(scimType, detail string) error {
+	return &requestError{status: http.StatusBadRequest, scimType: scimType, detail: detail}
+}
+
+func notFound(detail string) error {
+	return &requestError{status: http.StatusNotFound, detail: detail}
+}
+
+func conflict(detail string) error {
+	return &requestError{status: http.StatusConflict, scimType: errUniqueness, detail: detail}
+}
+
+func writeJSON(c *gin.Context, status int, body interface{}) {
+	c.Header("Content-Type", contentType)
+	c.JSON(status, body)
+}
+
+func writeError(c *gin.Context, status int, scimType, detail string) {
+	writeJSON(c, status, scimError{
+		Schemas:  []string{schemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	})
+}
+
+// fail writes err, hiding the details of unexpected errors.
+func fail(c *gin.Context, err error) {
+	if e, ok := err.(*requestError); ok {
+		writeError(c, e.status, e.scimType, e.detail)
+		return
+	}
+	slog.Error("SCIM request failed", "method", c.Request.Method, "path", c.Request.URL.Path, "error", err)
+	writeError(c, http.StatusInternalServerError, "", "internal error")
+}
+
+// bind decodes the request body into v.
+func bind(c *gin.Context, v interface{}) error {
+	if err := decodeJSON(c.Request.Body, v); err != nil {
+		return badRequest(errInvalidSyntax, "invalid request body: "+err.Error())
+	}
+	return nil
+}
+
+func (h *Handler) groupsAreFixed(c *gin.Context) {
+	writeError(c, http.StatusMethodNotAllowed, "", "groups are the fixed roles and can't be created or deleted")
+}
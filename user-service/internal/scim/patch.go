@@ -0,0 +1,289 @@
+package scim
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// patchRequest is the body of a PATCH request (RFC 7644 section 3.5.2).
+type patchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []patchOperation `json:"Operations"`
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// bindPatch decodes the body of a PATCH request.
+func bindPatch(c *gin.Context) (*patchRequest, error) {
+	var req patchRequest
+	if err := bind(c, &req); err != nil {
+		return nil, err
+	}
+	if !slices.Contains(req.Schemas, schemaPatchOp) {
+		return nil, badRequest(errInvalidSyntax, "schemas must contain "+schemaPatchOp)
+	}
+	return &req, nil
+}
+
+// patchPath is a parsed operation path: attr, attr.sub, attr[filter] or
+// attr[filter].sub. Filter attributes are relative to the filtered values.
+type patchPath struct {
+	attr   string
+	filter filter
+	sub    string
+}
+
+// parsePath parses path, returning nil for attributes of extension schemas,
+// which are ignored like other attributes without a counterpart.
+func parsePath(path string) (*patchPath, error) {
+	attrPart, rest, hasFilter := strings.Cut(path, "[")
+	attrPart = normalizeAttr(attrPart)
+	if strings.HasPrefix(attrPart, "urn:") {
+		return nil, nil
+	}
+
+	if !hasFilter {
+		attr, sub, _ := strings.Cut(attrPart, ".")
+		if attr == "" {
+			return nil, badRequest(errInvalidPath, "invalid path "+path)
+		}
+		return &patchPath{attr: attr, sub: sub}, nil
+	}
+
+	end := strings.LastIndex(rest, "]")
+	if end < 0 || attrPart == "" {
+		return nil, badRequest(errInvalidPath, "invalid path "+path)
+	}
+	f, err := parseFilter(rest[:end])
+	if err != nil {
+		return nil, badRequest(errInvalidPath, "invalid path "+path+": "+err.Error())
+	}
+	p := &patchPath{attr: attrPart, filter: f}
+	if after := rest[end+1:]; after != "" {
+		sub, ok := strings.CutPrefix(after, ".")
+		if !ok || sub == "" {
+			return nil, badRequest(errInvalidPath, "invalid path "+path)
+		}
+		p.sub = strings.ToLower(sub)
+	}
+	return p, nil
+}
+
+// applyPatch applies operations to doc, the JSON form of a resource.
+func applyPatch(doc map[string]interface{}, operations []patchOperation) error {
+	for _, op := range operations {
+		if err := applyOperation(doc, op); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(doc map[string]interface{}, op patchOperation) error {
+	name := strings.ToLower(op.Op)
+	if name != "add" && name != "replace" && name != "remove" {
+		return badRequest(errInvalidSyntax, "unknown op "+op.Op)
+	}
+
+	// Without a path the value holds the attributes to set. Its keys may be
+	// paths themselves, e.g. {"name.givenName": "Ann"}
+	if op.Path == "" {
+		if name == "remove" {
+			return badRequest(errNoTarget, "remove requires a path")
+		}
+		values, ok := op.Value.(map[string]interface{})
+		if !ok {
+			return badRequest(errInvalidValue, "value must be an object when there is no path")
+		}
+		for path, value := range values {
+			if err := applyOperation(doc, patchOperation{Op: name, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := parsePath(op.Path)
+	if err != nil || path == nil {
+		return err
+	}
+	key := findKey(doc, path.attr)
+
+	switch {
+	case path.filter != nil:
+		return applyFiltered(doc, key, path, name, op.Value)
+
+	case path.sub != "":
+		switch target := doc[key].(type) {
+		case map[string]interface{}:
+			setAttr(target, path.sub, name, op.Value)
+		case []interface{}:
+			// A sub-attribute of a multi-valued attribute targets every value
+			for _, v := range target {
+				if element, ok := v.(map[string]interface{}); ok {
+					setAttr(element, path.sub, name, op.Value)
+				}
+			}
+		case nil:
+			if name != "remove" {
+				doc[key] = map[string]interface{}{path.sub: op.Value}
+			}
+		default:
+			return badRequest(errInvalidPath, path.attr+" has no sub-attributes")
+		}
+
+	case name == "add":
+		// Adding to a multi-valued attribute appends
+		if existing, ok := doc[key].([]interface{}); ok {
+			if values, ok := op.Value.([]interface{}); ok {
+				doc[key] = append(existing, values...)
+			} else {
+				doc[key] = append(existing, op.Value)
+			}
+			return nil
+		}
+		doc[key] = op.Value
+
+	case name == "remove" && op.Value != nil:
+		// Some identity providers list the values to remove instead of using
+		// a filter, e.g. members with [{"value": "<id>"}]
+		existing, _ := doc[key].([]interface{})
+		removed, _ := op.Value.([]interface{})
+		doc[key] = slices.DeleteFunc(existing, func(v interface{}) bool {
+			element, _ := v.(map[string]interface{})
+			for _, r := range removed {
+				if r, ok := r.(map[string]interface{}); ok && element != nil && r[findKey(r, "value")] == element[findKey(element, "value")] {
+					return true
+				}
+			}
+			return false
+		})
+
+	default:
+		setAttr(doc, key, name, op.Value)
+	}
+	return nil
+}
+
+// applyFiltered applies an operation to the values of a multi-valued
+// attribute matching path.filter.
+func applyFiltered(doc map[string]interface{}, key string, path *patchPath, name string, value interface{}) error {
+	values, _ := doc[key].([]interface{})
+	kept := make([]interface{}, 0, len(values))
+	matched := 0
+	for _, v := range values {
+		element, ok := v.(map[string]interface{})
+		if ok {
+			ok, _ = matchFilter(path.filter, elementAttr(element))
+		}
+		if !ok {
+			kept = append(kept, v)
+			continue
+		}
+		matched++
+
+		switch {
+		case path.sub != "":
+			setAttr(element, path.sub, name, value)
+		case name == "remove":
+			continue
+		default:
+			replacement, ok := value.(map[string]interface{})
+			if !ok {
+				return badRequest(errInvalidValue, "value must be an object")
+			}
+			if name == "replace" {
+				element = map[string]interface{}{}
+			}
+			for k, v := range replacement {
+				element[findKey(element, k)] = v
+			}
+		}
+		kept = append(kept, element)
+	}
+
+	// Setting a value that doesn't exist yet, like emails[type eq "work"].value
+	// for a user without a work email, adds it
+	if matched == 0 && name != "remove" {
+		element, ok := filterElement(path.filter)
+		if !ok {
+			return badRequest(errNoTarget, "no value matches "+path.attr+" filter")
+		}
+		if path.sub != "" {
+			element[path.sub] = value
+		} else if replacement, ok := value.(map[string]interface{}); ok {
+			for k, v := range replacement {
+				element[findKey(element, k)] = v
+			}
+		} else {
+			return badRequest(errInvalidValue, "value must be an object")
+		}
+		kept = append(kept, element)
+	}
+
+	doc[key] = kept
+	return nil
+}
+
+// setAttr sets or, for remove, deletes the attribute name of m.
+func setAttr(m map[string]interface{}, name, op string, value interface{}) {
+	key := findKey(m, name)
+	if op == "remove" {
+		delete(m, key)
+		return
+	}
+	m[key] = value
+}
+
+// findKey returns the key of m matching name case-insensitively, or name if
+// there is none.
+func findKey(m map[string]interface{}, name string) string {
+	for k := range m {
+		if strings.EqualFold(k, name) {
+			return k
+		}
+	}
+	return name
+}
+
+func elementAttr(element map[string]interface{}) func(attr string) (interface{}, bool) {
+	return func(attr string) (interface{}, bool) {
+		v, ok := element[findKey(element, attr)]
+		return v, ok
+	}
+}
+
+// filterElement returns the value described by a filter made of eq
+// comparisons joined by and, such as type eq "work".
+func filterElement(f filter) (map[string]interface{}, bool) {
+	switch f := f.(type) {
+	case *attrFilter:
+		if f.op != "eq" || strings.Contains(f.attr, ".") {
+			return nil, false
+		}
+		return map[string]interface{}{f.attr: f.value}, true
+	case *logicalFilter:
+		if f.op != "and" {
+			return nil, false
+		}
+		left, ok := filterElement(f.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := filterElement(f.right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	}
+	return nil, false
+}
@@ -0,0 +1,265 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParsePath(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantAttr   string
+		wantSub    string
+		wantFilter bool
+	}{
+		{"displayName", "displayname", "", false},
+		{"name.givenName", "name", "givenname", false},
+		{"urn:ietf:params:scim:schemas:core:2.0:User:userName", "username", "", false},
+		{`emails[type eq "work"]`, "emails", "", true},
+		{`emails[type eq "work"].value`, "emails", "value", true},
+		{`members[value eq "x"].Display`, "members", "display", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			p, err := parsePath(tt.path)
+			if err != nil {
+				t.Fatalf("parsePath: %v", err)
+			}
+			if p.attr != tt.wantAttr || p.sub != tt.wantSub || (p.filter != nil) != tt.wantFilter {
+				t.Errorf("parsePath = {%q %q filter:%v}, want {%q %q filter:%v}",
+					p.attr, p.sub, p.filter != nil, tt.wantAttr, tt.wantSub, tt.wantFilter)
+			}
+		})
+	}
+}
+
+func TestParsePathExtension(t *testing.T) {
+	p, err := parsePath("urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department")
+	if err != nil || p != nil {
+		t.Errorf("parsePath = %v, %v, want extension attributes to be ignored", p, err)
+	}
+}
+
+func TestParsePathErrors(t *testing.T) {
+	tests := []string{
+		"",
+		".value",
+		`[type eq "work"]`,
+		`emails[type eq "work"`,
+		`emails[type eq]`,
+		`emails[type eq "work"]value`,
+		`emails[type eq "work"].`,
+	}
+	for _, path := range tests {
+		t.Run(path, func(t *testing.T) {
+			_, err := parsePath(path)
+			var reqErr *requestError
+			if !errors.As(err, &reqErr) || reqErr.scimType != errInvalidPath {
+				t.Errorf("parsePath(%q) error = %v, want %s", path, err, errInvalidPath)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	const user = `{
+		"userName": "ann@example.com",
+		"displayName": "Ann",
+		"name": {"givenName": "Ann", "familyName": "Smith"},
+		"emails": [
+			{"value": "ann@example.com", "type": "work", "primary": true},
+			{"value": "ann@home.example", "type": "home"}
+		],
+		"members": [{"value": "u1"}, {"value": "u2"}]
+	}`
+
+	tests := []struct {
+		name       string
+		operations string
+		want       string
+	}{
+		{
+			name:       "replace attribute",
+			operations: `[{"op": "replace", "path": "displayName", "value": "Annie"}]`,
+			want:       `{"displayName": "Annie"}`,
+		},
+		{
+			name:       "op and path ignore case",
+			operations: `[{"op": "Replace", "path": "DISPLAYNAME", "value": "Annie"}]`,
+			want:       `{"displayName": "Annie"}`,
+		},
+		{
+			name:       "remove attribute",
+			operations: `[{"op": "remove", "path": "displayName"}]`,
+			want:       `{"displayName": null}`,
+		},
+		{
+			name:       "replace sub-attribute",
+			operations: `[{"op": "replace", "path": "name.familyName", "value": "Jones"}]`,
+			want:       `{"name": {"givenName": "Ann", "familyName": "Jones"}}`,
+		},
+		{
+			name:       "add sub-attribute of a missing attribute",
+			operations: `[{"op": "add", "path": "address.locality", "value": "Berlin"}]`,
+			want:       `{"address": {"locality": "Berlin"}}`,
+		},
+		{
+			name:       "sub-attribute of every value",
+			operations: `[{"op": "remove", "path": "emails.type"}]`,
+			want: `{"emails": [
+				{"value": "ann@example.com", "primary": true},
+				{"value": "ann@home.example"}
+			]}`,
+		},
+		{
+			name:       "no path",
+			operations: `[{"op": "replace", "value": {"displayName": "Annie", "name.givenName": "Anne"}}]`,
+			want:       `{"displayName": "Annie", "name": {"givenName": "Anne", "familyName": "Smith"}}`,
+		},
+		{
+			name:       "add appends to multi-valued attributes",
+			operations: `[{"op": "add", "path": "members", "value": [{"value": "u3"}]}]`,
+			want:       `{"members": [{"value": "u1"}, {"value": "u2"}, {"value": "u3"}]}`,
+		},
+		{
+			name:       "add a single value",
+			operations: `[{"op": "add", "path": "members", "value": {"value": "u3"}}]`,
+			want:       `{"members": [{"value": "u1"}, {"value": "u2"}, {"value": "u3"}]}`,
+		},
+		{
+			name:       "remove listed values",
+			operations: `[{"op": "remove", "path": "members", "value": [{"value": "u1"}]}]`,
+			want:       `{"members": [{"value": "u2"}]}`,
+		},
+		{
+			name:       "remove filtered values",
+			operations: `[{"op": "remove", "path": "members[value eq \"u2\"]"}]`,
+			want:       `{"members": [{"value": "u1"}]}`,
+		},
+		{
+			name:       "replace sub-attribute of filtered values",
+			operations: `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "ann@new.example"}]`,
+			want: `{"emails": [
+				{"value": "ann@new.example", "type": "work", "primary": true},
+				{"value": "ann@home.example", "type": "home"}
+			]}`,
+		},
+		{
+			name:       "replace filtered values",
+			operations: `[{"op": "replace", "path": "emails[type eq \"home\"]", "value": {"value": "ann@other.example"}}]`,
+			want: `{"emails": [
+				{"value": "ann@example.com", "type": "work", "primary": true},
+				{"value": "ann@other.example"}
+			]}`,
+		},
+		{
+			name:       "add to filtered values merges",
+			operations: `[{"op": "add", "path": "emails[type eq \"home\"]", "value": {"display": "Home"}}]`,
+			want: `{"emails": [
+				{"value": "ann@example.com", "type": "work", "primary": true},
+				{"value": "ann@home.example", "type": "home", "display": "Home"}
+			]}`,
+		},
+		{
+			name:       "filter without a match adds the value",
+			operations: `[{"op": "replace", "path": "emails[type eq \"other\"].value", "value": "ann@other.example"}]`,
+			want: `{"emails": [
+				{"value": "ann@example.com", "type": "work", "primary": true},
+				{"value": "ann@home.example", "type": "home"},
+				{"value": "ann@other.example", "type": "other"}
+			]}`,
+		},
+		{
+			name:       "remove with a filter without a match",
+			operations: `[{"op": "remove", "path": "members[value eq \"u9\"]"}]`,
+			want:       `{}`,
+		},
+		{
+			name:       "extension attributes are ignored",
+			operations: `[{"op": "replace", "path": "urn:ietf:params:scim:schemas:extension:enterprise:2.0:User:department", "value": "R&D"}]`,
+			want:       `{}`,
+		},
+		{
+			name: "operations apply in order",
+			operations: `[
+				{"op": "replace", "path": "displayName", "value": "Annie"},
+				{"op": "remove", "path": "displayName"}
+			]`,
+			want: `{"displayName": null}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc, want map[string]interface{}
+			var operations []patchOperation
+			mustUnmarshal(t, user, &doc)
+			mustUnmarshal(t, user, &want)
+			mustUnmarshal(t, tt.operations, &operations)
+
+			// want lists the attributes that change; null means removed
+			var changes map[string]interface{}
+			mustUnmarshal(t, tt.want, &changes)
+			for k, v := range changes {
+				if v == nil {
+					delete(want, k)
+				} else {
+					want[k] = v
+				}
+			}
+
+			if err := applyPatch(doc, operations); err != nil {
+				t.Fatalf("applyPatch: %v", err)
+			}
+			if !reflect.DeepEqual(doc, want) {
+				got, _ := json.Marshal(doc)
+				expected, _ := json.Marshal(want)
+				t.Errorf("applyPatch =\n%s\nwant\n%s", got, expected)
+			}
+		})
+	}
+}
+
+func TestApplyPatchErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		operations string
+		wantType   string
+	}{
+		{"unknown op", `[{"op": "move", "path": "displayName", "value": "x"}]`, errInvalidSyntax},
+		{"remove without a path", `[{"op": "remove"}]`, errNoTarget},
+		{"no path and no object", `[{"op": "replace", "value": "x"}]`, errInvalidValue},
+		{"invalid path", `[{"op": "replace", "path": "emails[type eq", "value": "x"}]`, errInvalidPath},
+		{"sub-attribute of a simple attribute", `[{"op": "replace", "path": "displayName.x", "value": "x"}]`, errInvalidPath},
+		{"filtered value that isn't an object", `[{"op": "replace", "path": "emails[type eq \"work\"]", "value": "x"}]`, errInvalidValue},
+		{"filter that can't describe a new value", `[{"op": "add", "path": "emails[type ne \"work\" and type ne \"home\"].value", "value": "x"}]`, errNoTarget},
+		{"new value that isn't an object", `[{"op": "add", "path": "emails[type eq \"other\"]", "value": "x"}]`, errInvalidValue},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := map[string]interface{}{
+				"displayName": "Ann",
+				"emails": []interface{}{
+					map[string]interface{}{"value": "ann@example.com", "type": "work"},
+					map[string]interface{}{"value": "ann@home.example", "type": "home"},
+				},
+			}
+			var operations []patchOperation
+			mustUnmarshal(t, tt.operations, &operations)
+
+			err := applyPatch(doc, operations)
+			var reqErr *requestError
+			if !errors.As(err, &reqErr) || reqErr.scimType != tt.wantType {
+				t.Errorf("applyPatch error = %v, want %s", err, tt.wantType)
+			}
+		})
+	}
+}
+
+func mustUnmarshal(t *testing.T, data string, v interface{}) {
+	t.Helper()
+	if err := json.Unmarshal([]byte(data), v); err != nil {
+		t.Fatalf("invalid test JSON %s: %v", data, err)
+	}
+}
@@ -0,0 +1,219 @@
+package scim
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"user-service/internal/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Schema and message URNs.
+const (
+	schemaUser                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup                 = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaServiceProviderConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaResourceType          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	schemaListResponse          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaPatchOp               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	schemaError                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+type meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+// multiValue is an element of a multi-valued attribute such as emails.
+type multiValue struct {
+	Value   string    `json:"value"`
+	Display string    `json:"display,omitempty"`
+	Type    string    `json:"type,omitempty"`
+	Primary *scimBool `json:"primary,omitempty"`
+	Ref     string    `json:"$ref,omitempty"`
+}
+
+// scimBool also accepts "True" and "False" strings, which some identity
+// providers send for booleans.
+type scimBool bool
+
+func (b *scimBool) UnmarshalJSON(data []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case bool:
+		*b = scimBool(v)
+		return nil
+	case string:
+		switch strings.ToLower(v) {
+		case "true":
+			*b = true
+			return nil
+		case "false":
+			*b = false
+			return nil
+		}
+	}
+	return fmt.Errorf("%s is not a boolean", data)
+}
+
+func boolPtr(v bool) *scimBool {
+	b := scimBool(v)
+	return &b
+}
+
+// userResource is a User resource. Attributes without a counterpart in
+// model.User, like name or addresses, are ignored.
+//
+//	emails, userName  Email, taken from userName when no email is sent
+//	nickName          Username
+//	displayName       DisplayName
+//	title             JobTitle
+//	locale, timezone  Locale, Timezone
+//	photos            AvatarURL
+//	active            Status is active
+//	roles             Role
+//	groups            the group of Role, read-only
+type userResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id,omitempty"`
+	ExternalID  string       `json:"externalId,omitempty"`
+	UserName    string       `json:"userName"`
+	NickName    string       `json:"nickName,omitempty"`
+	DisplayName string       `json:"displayName,omitempty"`
+	Title       string       `json:"title,omitempty"`
+	Locale      string       `json:"locale,omitempty"`
+	Timezone    string       `json:"timezone,omitempty"`
+	Active      *scimBool    `json:"active,omitempty"`
+	Password    string       `json:"password,omitempty"`
+	Emails      []multiValue `json:"emails,omitempty"`
+	Photos      []multiValue `json:"photos,omitempty"`
+	Roles       []multiValue `json:"roles,omitempty"`
+	Groups      []multiValue `json:"groups,omitempty"`
+	Meta        *meta        `json:"meta,omitempty"`
+}
+
+// groupResource is a Group resource, one per role.
+type groupResource struct {
+	Schemas     []string     `json:"schemas"`
+	ID          string       `json:"id"`
+	DisplayName string       `json:"displayName"`
+	Members     []multiValue `json:"members,omitempty"`
+	Meta        *meta        `json:"meta,omitempty"`
+}
+
+type listResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int64       `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// resourceURL is the location of a resource, absolute as the RFC asks.
+func resourceURL(c *gin.Context, elems ...string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host + basePath + "/" + strings.Join(elems, "/")
+}
+
+func toUserResource(c *gin.Context, user *model.User) *userResource {
+	res := &userResource{
+		Schemas:  []string{schemaUser},
+		ID:       user.UserID,
+		UserName: user.Email,
+		NickName: user.Username,
+		Active:   boolPtr(user.Status == model.UserStatusActive),
+		Emails: []multiValue{
+			{Value: user.Email, Type: "work", Primary: boolPtr(true)},
+		},
+		Roles: []multiValue{
+			{Value: user.Role, Primary: boolPtr(true)},
+		},
+		Groups: []multiValue{
+			{Value: user.Role, Display: user.Role, Ref: resourceURL(c, "Groups", user.Role)},
+		},
+		Meta: &meta{
+			ResourceType: "User",
+			Created:      &user.CreatedAt,
+			LastModified: &user.UpdatedAt,
+			Location:     resourceURL(c, "Users", user.UserID),
+		},
+	}
+	if user.ExternalID != nil {
+		res.ExternalID = *user.ExternalID
+	}
+	if user.DisplayName != nil {
+		res.DisplayName = *user.DisplayName
+	}
+	if user.JobTitle != nil {
+		res.Title = *user.JobTitle
+	}
+	if user.Locale != nil {
+		res.Locale = *user.Locale
+	}
+	if user.Timezone != nil {
+		res.Timezone = *user.Timezone
+	}
+	if user.AvatarURL != nil {
+		res.Photos = []multiValue{{Value: *user.AvatarURL, Type: "photo", Primary: boolPtr(true)}}
+	}
+	return res
+}
+
+// primaryValue returns the primary value of a multi-valued attribute, or its
+// first value if none is marked primary.
+func primaryValue(values []multiValue) string {
+	for _, v := range values {
+		if v.Primary != nil && bool(*v.Primary) {
+			return v.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// decodeJSON decodes a request body, keeping numbers as json.Number.
+func decodeJSON(r io.Reader, v interface{}) error {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// toMap converts v to the generic form PATCH operations work on.
+func toMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := decodeJSON(bytes.NewReader(data), &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// fromMap converts the result of PATCH operations back into v.
+func fromMap(m map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return badRequest(errInvalidValue, err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,458 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"user-service/internal/account"
+	"user-service/internal/audit"
+	"user-service/internal/events"
+	"user-service/internal/model"
+	"user-service/internal/password"
+	"user-service/internal/profile"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCount = 100
+	maxCount     = 200
+	// maxUsernameLength is the size of the username column.
+	maxUsernameLength = 50
+)
+
+// userColumns are the attributes Users can be filtered on.
+var userColumns = map[string]column{
+	"id":           {sql: "user_id::text", kind: "string", caseExact: true},
+	"externalid":   {sql: "external_id", kind: "string", caseExact: true},
+	"username":     {sql: "email", kind: "string"},
+	"emails":       {sql: "email", kind: "string"},
+	"emails.value": {sql: "email", kind: "string"},
+	// The email is the only one and always the primary work email
+	"emails.type":       {sql: "'work'", kind: "string"},
+	"emails.primary":    {sql: "TRUE", kind: "bool"},
+	"nickname":          {sql: "username", kind: "string"},
+	"displayname":       {sql: "display_name", kind: "string"},
+	"title":             {sql: "job_title", kind: "string"},
+	"locale":            {sql: "locale", kind: "string"},
+	"timezone":          {sql: "timezone", kind: "string"},
+	"roles":             {sql: "role", kind: "string"},
+	"roles.value":       {sql: "role", kind: "string"},
+	"active":            {sql: "(status = 'active')", kind: "bool"},
+	"meta.created":      {sql: "created_at", kind: "time"},
+	"meta.lastmodified": {sql: "updated_at", kind: "time"},
+}
+
+// userChanges reports the lifecycle changes of an update.
+type userChanges struct {
+	roleChanged     bool
+	activated       bool
+	deactivated     bool
+	passwordChanged bool
+	// newPassword is set by updateUser, the way changePassword does
+	newPassword string
+}
+
+func (h *Handler) listUsers(c *gin.Context) {
+	startIndex, count, err := pagination(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	query := h.db.WithContext(c.Request.Context()).Model(&model.User{})
+	if s := c.Query("filter"); s != "" {
+		f, err := parseFilter(s)
+		if err != nil {
+			fail(c, badRequest(errInvalidFilter, err.Error()))
+			return
+		}
+		where, args, err := sqlFilter(f, userColumns)
+		if err != nil {
+			fail(c, badRequest(errInvalidFilter, err.Error()))
+			return
+		}
+		query = query.Where(where, args...)
+	}
+	query = query.Session(&gorm.Session{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		fail(c, fmt.Errorf("failed to count users: %w", err))
+		return
+	}
+	var users []model.User
+	if count > 0 {
+		if err := query.Order("created_at, user_id").Offset(startIndex - 1).Limit(count).Find(&users).Error; err != nil {
+			fail(c, fmt.Errorf("failed to list users: %w", err))
+			return
+		}
+	}
+
+	resources := make([]*userResource, len(users))
+	for i := range users {
+		resources[i] = toUserResource(c, &users[i])
+	}
+	writeJSON(c, http.StatusOK, listResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// pagination reads the 1-based startIndex and count query parameters.
+func pagination(c *gin.Context) (startIndex, count int, err error) {
+	startIndex, count = 1, defaultCount
+	if s := c.Query("startIndex"); s != "" {
+		if startIndex, err = strconv.Atoi(s); err != nil {
+			return 0, 0, badRequest(errInvalidValue, "startIndex must be an integer")
+		}
+		startIndex = max(startIndex, 1)
+	}
+	if s := c.Query("count"); s != "" {
+		if count, err = strconv.Atoi(s); err != nil {
+			return 0, 0, badRequest(errInvalidValue, "count must be an integer")
+		}
+		count = min(max(count, 0), maxCount)
+	}
+	return startIndex, count, nil
+}
+
+func (h *Handler) getUser(c *gin.Context) {
+	user, err := h.findUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	writeJSON(c, http.StatusOK, toUserResource(c, user))
+}
+
+func (h *Handler) findUser(ctx context.Context, id string) (*model.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, notFound("user not found")
+	}
+	var user model.User
+	err := h.db.WithContext(ctx).Where("user_id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, notFound("user not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	return &user, nil
+}
+
+// createUser provisions an active user, or a deactivated one when active is
+// false. The identity provider vouches for the email, so it counts as
+// verified. Without a password, the user can set one through a password reset.
+func (h *Handler) createUser(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var res userResource
+	if err := bind(c, &res); err != nil {
+		fail(c, err)
+		return
+	}
+
+	user := &model.User{
+		Role:   model.RoleMember,
+		Status: model.UserStatusActive,
+	}
+	if _, err := h.applyUser(ctx, user, &res); err != nil {
+		fail(c, err)
+		return
+	}
+	if err := h.db.WithContext(ctx).Create(user).Error; err != nil {
+		fail(c, fmt.Errorf("failed to create user: %w", err))
+		return
+	}
+
+	h.events.Publish(ctx, events.UserCreated, user, performedBy(c))
+
+	c.Header("Location", resourceURL(c, "Users", user.UserID))
+	writeJSON(c, http.StatusCreated, toUserResource(c, user))
+}
+
+// replaceUser replaces the attributes of a user. Profile attributes left out
+// are cleared; nickName, roles and active keep their current value.
+func (h *Handler) replaceUser(c *gin.Context) {
+	user, err := h.findUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	var res userResource
+	if err := bind(c, &res); err != nil {
+		fail(c, err)
+		return
+	}
+	h.updateUser(c, user, &res)
+}
+
+func (h *Handler) patchUser(c *gin.Context) {
+	user, err := h.findUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	req, err := bindPatch(c)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	current := toUserResource(c, user)
+	doc, err := toMap(current)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+	if err := applyPatch(doc, req.Operations); err != nil {
+		fail(c, err)
+		return
+	}
+	var res userResource
+	if err := fromMap(doc, &res); err != nil {
+		fail(c, err)
+		return
+	}
+
+	// The current email is also in emails, so a patch of userName alone would
+	// be overridden by it
+	if res.UserName != current.UserName && primaryValue(res.Emails) == current.UserName {
+		res.Emails = nil
+	}
+	// Every user has a role, removing it falls back to the least privileged
+	if len(res.Roles) == 0 {
+		res.Roles = []multiValue{{Value: model.RoleMember}}
+	}
+	h.updateUser(c, user, &res)
+}
+
+// updateUser applies res to user and runs the side effects of the lifecycle
+// changes, as the corresponding GraphQL mutations do.
+func (h *Handler) updateUser(c *gin.Context, user *model.User, res *userResource) {
+	ctx := c.Request.Context()
+
+	changes, err := h.applyUser(ctx, user, res)
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	err = h.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(user).Error; err != nil {
+			return err
+		}
+		if changes.passwordChanged {
+			if err := account.SetPassword(tx, h.passwords, user, changes.newPassword); err != nil {
+				return err
+			}
+		}
+		if changes.deactivated || changes.passwordChanged {
+			return account.RevokeTokens(tx, user.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		fail(c, fmt.Errorf("failed to update user: %w", err))
+		return
+	}
+
+	// Access tokens carry the role, so force a refresh to pick up the new one
+	if changes.roleChanged || changes.deactivated || changes.passwordChanged {
+		if err := h.revocations.RevokeUser(ctx, user.UserID); err != nil {
+			fail(c, fmt.Errorf("failed to revoke tokens: %w", err))
+			return
+		}
+	}
+	if changes.passwordChanged {
+		h.audit.Record(ctx, audit.PasswordChanged, user.UserID, user.Email, "provisioned")
+	}
+
+	published := false
+	publish := func(changed bool, eventType string) {
+		if changed {
+			h.events.Publish(ctx, eventType, user, performedBy(c))
+			published = true
+		}
+	}
+	publish(changes.roleChanged, events.UserRoleChanged)
+	publish(changes.deactivated, events.UserDeactivated)
+	publish(changes.activated, events.UserReactivated)
+	publish(!published, events.UserUpdated)
+
+	writeJSON(c, http.StatusOK, toUserResource(c, user))
+}
+
+// deleteUser deletes a user like the deleteUser mutation.
+func (h *Handler) deleteUser(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	user, err := h.findUser(ctx, c.Param("id"))
+	if err != nil {
+		fail(c, err)
+		return
+	}
+
+	if err := account.Delete(h.db.WithContext(ctx), user); err != nil {
+		fail(c, fmt.Errorf("failed to delete user: %w", err))
+		return
+	}
+
+	if err := h.revocations.RevokeUser(ctx, user.UserID); err != nil {
+		fail(c, fmt.Errorf("failed to revoke tokens: %w", err))
+		return
+	}
+
+	h.events.Publish(ctx, events.UserDeleted, user, performedBy(c))
+
+	c.Status(http.StatusNoContent)
+}
+
+// applyUser validates res and copies it onto user, without saving it.
+func (h *Handler) applyUser(ctx context.Context, user *model.User, res *userResource) (*userChanges, error) {
+	db := h.db.WithContext(ctx)
+	changes := &userChanges{}
+
+	email := strings.TrimSpace(primaryValue(res.Emails))
+	if email == "" {
+		email = strings.TrimSpace(res.UserName)
+	}
+	if email == "" {
+		return nil, badRequest(errInvalidValue, "userName is required")
+	}
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, badRequest(errInvalidValue, "userName must be an email address")
+	}
+	if email != user.Email {
+		var count int64
+		if err := db.Model(&model.User{}).Where("email = ? AND user_id <> ?", email, user.UserID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, conflict("email already in use")
+		}
+		// The identity provider vouches for the address
+		user.Email = email
+		user.EmailVerified = true
+	}
+
+	if externalID := strings.TrimSpace(res.ExternalID); externalID == "" {
+		user.ExternalID = nil
+	} else if user.ExternalID == nil || *user.ExternalID != externalID {
+		var count int64
+		if err := db.Model(&model.User{}).Where("external_id = ? AND user_id <> ?", externalID, user.UserID).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			return nil, conflict("externalId already in use")
+		}
+		user.ExternalID = &externalID
+	}
+
+	if username := strings.TrimSpace(res.NickName); username != "" {
+		user.Username = truncate(username, maxUsernameLength)
+	} else if user.Username == "" {
+		local, _, _ := strings.Cut(email, "@")
+		user.Username = truncate(local, maxUsernameLength)
+	}
+
+	var err error
+	set := func(field **string, value string, normalize func(string) (string, error)) {
+		value = strings.TrimSpace(value)
+		if err != nil {
+			return
+		}
+		if value == "" {
+			*field = nil
+			return
+		}
+		if value, err = normalize(value); err == nil {
+			*field = &value
+		}
+	}
+	set(&user.DisplayName, res.DisplayName, profile.Text("displayName"))
+	set(&user.JobTitle, res.Title, profile.Text("title"))
+	set(&user.Locale, res.Locale, profile.NormalizeLocale)
+	set(&user.Timezone, res.Timezone, profile.NormalizeTimezone)
+	set(&user.AvatarURL, primaryValue(res.Photos), profile.NormalizeAvatarURL)
+	if err != nil {
+		return nil, badRequest(errInvalidValue, err.Error())
+	}
+
+	// Users have a single role; when several are sent the last one wins
+	if len(res.Roles) > 0 {
+		role := strings.ToLower(strings.TrimSpace(res.Roles[len(res.Roles)-1].Value))
+		if !model.IsValidRole(role) {
+			return nil, badRequest(errInvalidValue, "unknown role "+role)
+		}
+		changes.roleChanged = user.UserID != "" && role != user.Role
+		user.Role = role
+	}
+
+	if res.Active != nil {
+		active := bool(*res.Active)
+		switch {
+		case user.Status == model.UserStatusPending:
+			if active {
+				return nil, &requestError{
+					status:   http.StatusBadRequest,
+					scimType: errMutability,
+					detail:   "user has not accepted their invitation yet",
+				}
+			}
+		case active && user.Status != model.UserStatusActive:
+			changes.activated = user.UserID != ""
+			user.Status = model.UserStatusActive
+		case !active && user.Status == model.UserStatusActive:
+			changes.deactivated = user.UserID != ""
+			user.Status = model.UserStatusDeactivated
+		}
+	}
+
+	if res.Password != "" {
+		if err := h.passwords.Validate(res.Password); err != nil {
+			return nil, badRequest(errInvalidValue, err.Error())
+		}
+		if user.UserID == "" {
+			hash, err := h.passwords.Hash(res.Password)
+			if err != nil {
+				return nil, errors.New("failed to hash password")
+			}
+			user.PasswordHash = hash
+		} else {
+			err := account.CheckNewPassword(db, h.passwords, user, res.Password)
+			if errors.Is(err, password.ErrReused) {
+				return nil, badRequest(errInvalidValue, err.Error())
+			}
+			if err != nil {
+				return nil, err
+			}
+			changes.passwordChanged = true
+			changes.newPassword = res.Password
+		}
+	}
+
+	return changes, nil
+}
+
+func truncate(s string, n int) string {
+	for utf8.RuneCountInString(s) > n {
+		_, size := utf8.DecodeLastRuneInString(s)
+		s = s[:len(s)-size]
+	}
+	return s
+}